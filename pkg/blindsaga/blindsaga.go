@@ -2,35 +2,51 @@ package blindsaga
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// forwardBackoff есть минимальная пауза между попытками повторяемого этапа, повторяющегося до успеха.
+const forwardBackoff = 100 * time.Millisecond
+
 // BlindSaga является простейшим оркестратором паттерна "сага":
 // позволяет синхронизировать HTTP-уведомления о необходимости выполнения или отката определённого действия.
 // Позволяет организовать т.н. "слепую сагу", то есть распределённую транзакцию, успех этапов которой определяется косвенно.
+// Неудача откатываемого или поворотного этапа приводит к откату предыдущих этапов (обратное восстановление),
+// неудача повторяемого этапа — к его повтору (прямое восстановление).
 type BlindSaga struct {
 	stages    []*Stage     // Этапы саги.
 	client    *http.Client //
 	hostStage *Stage       // Информация об оркестраторе.
 	sagaName  string       // Имя саги, переданное извне.
 	sagaID    string       // Уникальный идентификатор саги.
+	history   *History     // Хранилище истории исполнения; может отсутствовать.
+
+	deadline             time.Duration // Ограничение времени прямого прохождения.
+	compensationDeadline time.Duration // Ограничение времени отката.
 }
 
 // New создаёт новую сагу на основе конфигурации. Если HTTP-клиент равен nil, то сага будет использовать http.DefaultClient.
-func New(config *Config, httpClient *http.Client) (*BlindSaga, error) {
+func New(config *Config, httpClient *http.Client, options ...Option) (*BlindSaga, error) {
 	if len(config.Stages) == 0 {
 		return nil, fmt.Errorf("empty saga")
 	}
 	newBlindSaga := &BlindSaga{
-		hostStage: &Stage{config.HostStage.Name, config.HostStage.Address},
+		hostStage: &Stage{Name: config.HostStage.Name, Address: config.HostStage.Address},
 		sagaName:  config.SagaName,
 		sagaID:    uuid.NewString(),
 		client:    httpClient,
+
+		deadline:             config.Deadline,
+		compensationDeadline: config.CompensationDeadline,
 	}
 	if httpClient == nil {
 		newBlindSaga.client = http.DefaultClient
@@ -41,14 +57,32 @@ func New(config *Config, httpClient *http.Client) (*BlindSaga, error) {
 			return nil, fmt.Errorf("non-unique stage name %v", v.Name)
 		}
 		uniqueCheck[v.Name] = struct{}{}
-		newBlindSaga.stages = append(newBlindSaga.stages, &Stage{v.Name, v.Address})
+		stage := v
+		newBlindSaga.stages = append(newBlindSaga.stages, &stage)
+	}
+	if _, err := validateKinds(config.Stages); err != nil {
+		return nil, err
+	}
+	for _, v := range options {
+		v(newBlindSaga)
 	}
 	return newBlindSaga, nil
 }
 
+// ID возвращает уникальный идентификатор саги.
+func (slf *BlindSaga) ID() string { return slf.sagaID }
+
 // Start запускает сагу с некоторой начальной информацией, возвращает накопленную метаинформацию и флаг успеха.
 // Одновременно может быть запущено несколько саг.
 func (slf *BlindSaga) Start(meta []byte) (*Meta, bool) {
+	return slf.StartContext(context.Background(), meta)
+}
+
+// StartContext запускает сагу с учётом контекста, возвращает накопленную метаинформацию и флаг успеха.
+// Время до крайнего срока прямого прохождения делится поровну между оставшимися этапами, время отката — между откатываемыми.
+// Этапы, не ответившие в отведённое время или не успевшие получить уведомление об откате, перечисляются в Meta.Unknown,
+// а сага завершается со статусом StatusUnknown.
+func (slf *BlindSaga) StartContext(ctx context.Context, meta []byte) (*Meta, bool) {
 	// Формируем уведомление о необходимости выполнить действие.
 	notification := &Notification{
 		SagaName:      slf.sagaName,
@@ -57,51 +91,198 @@ func (slf *BlindSaga) Start(meta []byte) (*Meta, bool) {
 		Action:        ActionDo,
 		Meta:          &Meta{Straight: map[string][]byte{slf.hostStage.Name: meta}},
 	}
+	slf.history.begin(notification)
+	if slf.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, slf.deadline)
+		defer cancel()
+	}
 	failedStage := -1
 	// Проход по этапам саги в прямом направлении.
 	for i, stage := range slf.stages {
-		meta, err := send(stage, notification)
+		retry := stage.Retry
+		if stage.Kind == KindRetriable {
+			retry = forward(retry)
+		}
+		stageCtx, cancel := share(ctx, len(slf.stages)-i)
+		meta, err := slf.send(stageCtx, stage, notification, retry)
+		cancel()
 		if len(meta) != 0 {
 			notification.Meta.Straight[stage.Name] = meta
 		}
 		// При возникновении ошибки останавливаем сагу.
 		if err != nil {
-			notification.Action = ActionUndo
 			notification.FailedStage = &FailedStage{Stage: stage, Details: err.Error()}
+			if interrupted(err) {
+				notification.Meta.Unknown = append(notification.Meta.Unknown, stage.Name)
+			}
 			failedStage = i
 			break
 		}
 	}
 	// При неудаче на определённом этапе саги уведомляем предыдущие этапы об отмене действия в обратном порядке.
-	if failedStage != 0 {
+	// Неудача повторяемого этапа означает, что поворотный этап пройден и откат невозможен.
+	if failedStage > 0 && slf.stages[failedStage].Kind != KindRetriable {
+		notification.Action = ActionUndo
 		notification.Meta.Undo = map[string][]byte{}
+		// Время отката не зависит от контекста прямого прохождения, который к этому моменту может быть исчерпан.
+		ctx, cancel := context.WithoutCancel(ctx), context.CancelFunc(func() {})
+		if slf.compensationDeadline > 0 {
+			ctx, cancel = context.WithTimeout(ctx, slf.compensationDeadline)
+		}
+		defer cancel()
+		// Время отката делится только между этапами, которым требуется откат.
+		left := 0
+		for _, stage := range slf.stages[:failedStage] {
+			if !stage.SkipUndo {
+				left++
+			}
+		}
 		for i := failedStage - 1; i >= 0; i-- {
-			meta, err := send(slf.stages[i], notification)
+			if slf.stages[i].SkipUndo {
+				continue
+			}
+			left--
+			// Этапы, до которых не дошла очередь отката, остаются в неизвестном состоянии.
+			if ctx.Err() != nil {
+				notification.Meta.Unknown = append(notification.Meta.Unknown, slf.stages[i].Name)
+				continue
+			}
+			stageCtx, cancel := share(ctx, left+1)
+			meta, err := slf.send(stageCtx, slf.stages[i], notification, slf.stages[i].UndoRetry)
+			cancel()
 			if err != nil {
 				meta = []byte(err.Error())
+				if interrupted(err) {
+					notification.Meta.Unknown = append(notification.Meta.Unknown, slf.stages[i].Name)
+				}
 			}
 			notification.Meta.Undo[slf.stages[i].Name] = meta
 		}
 	}
+	switch {
+	case failedStage == -1:
+		notification.Meta.Status = StatusSucceeded
+	case len(notification.Meta.Unknown) != 0:
+		notification.Meta.Status = StatusUnknown
+	default:
+		notification.Meta.Status = StatusFailed
+	}
+	slf.history.finish(notification.TransactionID, notification.Meta.Status)
 	return notification.Meta, failedStage == -1
 }
 
-// send осуществляет отправку уведомления по HTTP.
-func send(stage *Stage, notification *Notification) ([]byte, error) {
+// share возвращает контекст этапа, получающего равную долю времени, оставшегося до крайнего срока, среди оставшихся этапов.
+func share(ctx context.Context, left int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(left))
+}
+
+// interrupted сообщает, была ли ошибка вызвана истечением времени, из-за чего результат запроса к этапу неизвестен.
+func interrupted(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// forward дополняет политику повтора повторяемого этапа: при незаданном количестве попыток этап повторяется
+// до успеха или постоянной ошибки.
+func forward(retry Retry) Retry {
+	if retry.Attempts == 0 {
+		retry.Attempts = math.MaxInt
+		retry.Backoff = max(retry.Backoff, forwardBackoff)
+	}
+	return retry
+}
+
+// validateKinds проверяет порядок видов этапов: откатываемые этапы, не более одного поворотного, повторяемые этапы.
+// Возвращает индекс этапа, нарушающего порядок.
+func validateKinds(stages []Stage) (int, error) {
+	forwardOnly := false
+	pivot := false
+	for i, v := range stages {
+		switch v.Kind {
+		case "", KindCompensatable:
+			if forwardOnly {
+				return i, fmt.Errorf("compensatable stage %v after pivot or retriable stage", v.Name)
+			}
+		case KindPivot:
+			if pivot || forwardOnly {
+				return i, fmt.Errorf("pivot stage %v after pivot or retriable stage", v.Name)
+			}
+			pivot, forwardOnly = true, true
+		case KindRetriable:
+			forwardOnly = true
+		default:
+			return i, fmt.Errorf("unknown kind %q of stage %v", v.Kind, v.Name)
+		}
+	}
+	return -1, nil
+}
+
+// send осуществляет отправку уведомления этапу по HTTP с учётом политики повтора.
+// Попытки прекращаются при отмене контекста и при постоянной ошибке. Если хотя бы одна попытка не получила ответа вовремя,
+// ошибка последней попытки дополняется ошибкой истечения времени, поскольку результат запроса к этапу неизвестен.
+func (slf *BlindSaga) send(ctx context.Context, stage *Stage, notification *Notification, retry Retry) ([]byte, error) {
 	body, err := json.Marshal(notification)
 	if err != nil {
 		return nil, err
 	}
-	res, err := http.Post(stage.Address, ct, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
+	var timeout error
+	for attempt := 1; ; attempt++ {
+		step := Step{Stage: stage.Name, Action: notification.Action, Attempt: attempt, Start: time.Now().UTC()}
+		meta, code, err := slf.post(ctx, stage, body)
+		step.End, step.StatusCode = time.Now().UTC(), code
+		if err != nil {
+			step.Error = err.Error()
+		}
+		slf.history.step(notification.TransactionID, step)
+		if interrupted(err) {
+			timeout = err
+		}
+		if err != nil && timeout != nil && !interrupted(err) {
+			err = errors.Join(err, timeout)
+		}
+		if err == nil || attempt >= retry.Attempts || ctx.Err() != nil || permanent(code) {
+			return meta, err
+		}
+		select {
+		case <-time.After(retry.Backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %v", res.StatusCode)
+}
+
+// permanent сообщает, отвергнут ли запрос этапом так, что повтор не изменит результата:
+// ошибки клиента, кроме истечения времени запроса и превышения частоты запросов.
+func permanent(code int) bool {
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// post осуществляет единичный HTTP-запрос к этапу с учётом ограничения времени.
+// Помимо метаинформации возвращает код ответа, если ответ был получен.
+func (slf *BlindSaga) post(ctx context.Context, stage *Stage, body []byte) ([]byte, int, error) {
+	cancel := context.CancelFunc(func() {})
+	if stage.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, stage.Timeout)
 	}
-	body, err = io.ReadAll(res.Body)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, stage.Address, bytes.NewReader(body))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", ct)
+	res, err := slf.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, res.StatusCode, fmt.Errorf("unexpected status code %v", res.StatusCode)
 	}
-	return body, nil
+	meta, err := io.ReadAll(res.Body)
+	return meta, res.StatusCode, err
 }
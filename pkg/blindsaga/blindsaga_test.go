@@ -1,6 +1,7 @@
 package blindsaga
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -22,18 +24,45 @@ type testServer struct {
 	_ID      string
 	hostCode int
 	willFail bool
+	status   int           // Код ответа при ошибке; по умолчанию 500.
+	requests int           // Количество полученных запросов.
+	failures int           // Количество первых запросов, завершающихся ошибкой.
+	delay    time.Duration // Задержка ответа.
+	timeouts int           // Количество первых запросов, на которые этап не отвечает до отмены запроса.
 	counter  *struct{ n int }
 }
 
 func (slf *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Клиент прерывает запрос, который сервер ещё обрабатывает, только после прочтения тела.
+	body, err := io.ReadAll(r.Body)
+	assert.NoError(slf.t, err)
+	slf.requests++
+	if slf.timeouts > 0 {
+		slf.timeouts--
+		<-r.Context().Done()
+		return
+	}
+
 	// Эмулируем ошибку на этапе.
 	if slf.willFail {
+		w.WriteHeader(cmp.Or(slf.status, http.StatusInternalServerError))
+		return
+	}
+	if slf.failures > 0 {
+		slf.failures--
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	assert.NoError(slf.t, err)
+	// Эмулируем медленный этап. Если оркестратор не дождался ответа, запрос не обрабатывается.
+	if slf.delay > 0 {
+		select {
+		case <-time.After(slf.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
 	n := &Notification{}
 	assert.NoError(slf.t, json.Unmarshal(body, &n))
 
@@ -68,8 +97,8 @@ func TestBlindSaga(t *testing.T) {
 			server := &testServer{t: t, _ID: uuid.NewString(), counter: counter}
 			server.server = httptest.NewServer(server)
 			servers = append(servers, server)
-			expectedMeta[servers[i]._ID] = fmt.Append(nil, i)                           // Каждый сервер должен прислать свой порядковый номер.
-			config.Stages = append(config.Stages, Stage{server._ID, server.server.URL}) // Добавляем сервер в конфиг в порядке возрастания.
+			expectedMeta[servers[i]._ID] = fmt.Append(nil, i)                                          // Каждый сервер должен прислать свой порядковый номер.
+			config.Stages = append(config.Stages, Stage{Name: server._ID, Address: server.server.URL}) // Добавляем сервер в конфиг в порядке возрастания.
 			if failure && i == 9 {
 				server.willFail = true
 			}
@@ -121,3 +150,188 @@ func TestBlindSaga(t *testing.T) {
 	t.Run("success", func(t *testing.T) { testFlow(false) })
 	t.Run("failure", func(t *testing.T) { testFlow(true) })
 }
+
+func TestBlindSagaStageKinds(t *testing.T) {
+	// newSaga создаёт сагу из этапов заданных видов, каждый из которых обслуживается тестовым сервером.
+	newSaga := func(kinds []string, setup func(i int, server *testServer, stage *Stage)) (*BlindSaga, *struct{ n int }) {
+		config := &Config{HostStage: Stage{Name: hostName}, SagaName: uuid.NewString()}
+		counter := &struct{ n int }{}
+		for i, kind := range kinds {
+			server := &testServer{t: t, _ID: uuid.NewString(), counter: counter}
+			server.server = httptest.NewServer(server)
+			t.Cleanup(server.server.Close)
+			stage := Stage{Name: server._ID, Address: server.server.URL, Kind: kind}
+			setup(i, server, &stage)
+			config.Stages = append(config.Stages, stage)
+		}
+		s, err := New(config, nil)
+		assert.NoError(t, err)
+		return s, counter
+	}
+	kinds := []string{KindCompensatable, KindCompensatable, KindPivot, KindRetriable, KindRetriable}
+
+	t.Run("pivot failure", func(t *testing.T) {
+		s, counter := newSaga(kinds, func(i int, server *testServer, _ *Stage) { server.willFail = i == 2 })
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.False(t, ok)
+		// Откатываемые этапы до поворотного откатаны.
+		assert.Zero(t, counter.n)
+		assert.Len(t, meta.Undo, 2)
+		assert.Equal(t, s.stages[2].Name, meta.FailedStage.Name)
+	})
+
+	t.Run("retriable recovery", func(t *testing.T) {
+		s, counter := newSaga(kinds, func(i int, server *testServer, stage *Stage) {
+			if i == 4 {
+				server.failures = 3
+				stage.Retry.Backoff = time.Millisecond
+			}
+		})
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.True(t, ok)
+		// Повторяемый этап повторялся до успеха, откатов не было.
+		assert.Equal(t, 5, counter.n)
+		assert.Nil(t, meta.Undo)
+		assert.Nil(t, meta.FailedStage)
+	})
+
+	t.Run("retriable exhaustion", func(t *testing.T) {
+		s, counter := newSaga(kinds, func(i int, server *testServer, stage *Stage) {
+			if i == 3 {
+				server.willFail = true
+				stage.Retry = Retry{Attempts: 3}
+			}
+		})
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.False(t, ok)
+		// После поворотного этапа откат невозможен.
+		assert.Equal(t, 3, counter.n)
+		assert.Nil(t, meta.Undo)
+		assert.Equal(t, s.stages[3].Name, meta.FailedStage.Name)
+	})
+
+	t.Run("retriable rejection", func(t *testing.T) {
+		var rejecting *testServer
+		s, _ := newSaga(kinds, func(i int, server *testServer, stage *Stage) {
+			if i == 3 {
+				server.willFail, server.status = true, http.StatusBadRequest
+				rejecting = server
+			}
+		})
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.False(t, ok)
+		// Постоянная ошибка не повторяется даже без ограничения числа попыток.
+		assert.Equal(t, 1, rejecting.requests)
+		assert.Equal(t, s.stages[3].Name, meta.FailedStage.Name)
+	})
+
+	t.Run("retriable timeout", func(t *testing.T) {
+		s, _ := newSaga(kinds, func(i int, server *testServer, stage *Stage) {
+			if i == 3 {
+				server.timeouts, server.willFail = 1, true
+				stage.Timeout = 20 * time.Millisecond
+				stage.Retry = Retry{Attempts: 2}
+			}
+		})
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.False(t, ok)
+		// Первая попытка не получила ответа, и этап мог выполнить действие, поэтому его состояние неизвестно,
+		// хотя последняя попытка завершилась ошибкой.
+		assert.Equal(t, StatusUnknown, meta.Status)
+		assert.Equal(t, []string{s.stages[3].Name}, meta.Unknown)
+	})
+
+	t.Run("invalid order", func(t *testing.T) {
+		for _, kinds := range [][]string{
+			{KindPivot, KindCompensatable},
+			{KindRetriable, KindPivot},
+			{KindPivot, KindPivot},
+			{"unknown"},
+		} {
+			config := &Config{HostStage: Stage{Name: hostName}}
+			for i, kind := range kinds {
+				config.Stages = append(config.Stages, Stage{Name: fmt.Sprint(i), Kind: kind})
+			}
+			_, err := New(config, nil)
+			assert.Error(t, err, kinds)
+		}
+	})
+}
+
+func TestBlindSagaDeadline(t *testing.T) {
+	newSaga := func(config *Config, setup func(i int, server *testServer)) *BlindSaga {
+		config.HostStage, config.SagaName = Stage{Name: hostName}, uuid.NewString()
+		counter := &struct{ n int }{}
+		for i := range 3 {
+			server := &testServer{t: t, _ID: uuid.NewString(), counter: counter}
+			setup(i, server)
+			server.server = httptest.NewServer(server)
+			t.Cleanup(server.server.Close)
+			config.Stages = append(config.Stages, Stage{Name: server._ID, Address: server.server.URL})
+		}
+		s, err := New(config, nil)
+		assert.NoError(t, err)
+		return s
+	}
+
+	t.Run("saga deadline", func(t *testing.T) {
+		s := newSaga(&Config{Deadline: 300 * time.Millisecond}, func(i int, server *testServer) {
+			if i == 2 {
+				server.delay = time.Second
+			}
+		})
+		start := time.Now()
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.False(t, ok)
+		// Последний этап не ответил вовремя, предыдущие откатаны.
+		assert.Equal(t, StatusUnknown, meta.Status)
+		assert.Equal(t, []string{s.stages[2].Name}, meta.Unknown)
+		assert.Len(t, meta.Undo, 2)
+	})
+
+	t.Run("compensation deadline", func(t *testing.T) {
+		s := newSaga(&Config{CompensationDeadline: 200 * time.Millisecond}, func(i int, server *testServer) {
+			switch i {
+			case 0:
+				server.delay = 50 * time.Millisecond
+			case 1:
+				server.delay = 250 * time.Millisecond
+			case 2:
+				server.willFail = true
+			}
+		})
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.False(t, ok)
+		// Откат второго этапа не уложился в отведённую ему долю времени, первый этап откатан.
+		assert.Equal(t, StatusUnknown, meta.Status)
+		assert.Equal(t, []string{s.stages[1].Name}, meta.Unknown)
+		assert.Equal(t, []byte("2"), meta.Undo[s.stages[0].Name])
+	})
+
+	t.Run("compensation deadline skip undo", func(t *testing.T) {
+		s := newSaga(&Config{CompensationDeadline: 300 * time.Millisecond}, func(i int, server *testServer) {
+			switch i {
+			case 1:
+				server.delay = 200 * time.Millisecond
+			case 2:
+				server.willFail = true
+			}
+		})
+		s.stages[0].SkipUndo = true
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.False(t, ok)
+		// Этап без отката не занимает долю времени отката, поэтому второму этапу достаётся всё время.
+		assert.Equal(t, StatusFailed, meta.Status)
+		assert.Empty(t, meta.Unknown)
+		assert.NotContains(t, meta.Undo, s.stages[0].Name)
+	})
+
+	t.Run("no deadline", func(t *testing.T) {
+		s := newSaga(&Config{}, func(i int, server *testServer) { server.willFail = i == 2 })
+		meta, ok := s.Start(fmt.Append(nil, 1))
+		assert.False(t, ok)
+		assert.Equal(t, StatusFailed, meta.Status)
+		assert.Empty(t, meta.Unknown)
+	})
+}
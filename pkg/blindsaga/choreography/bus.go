@@ -0,0 +1,28 @@
+package choreography
+
+import "licklib/pkg/blindsaga"
+
+const (
+	KindDone   = "done"   // Этап успешно совершил действие.
+	KindFailed = "failed" // Этап не смог совершить действие.
+	KindUndone = "undone" // Этап откатил действие.
+)
+
+// Event есть событие саги, публикуемое этапом в шину.
+type Event struct {
+	Topic        string
+	Notification *blindsaga.Notification
+}
+
+// Bus есть шина событий, через которую взаимодействуют этапы хореографической саги.
+type Bus interface {
+	// Publish доставляет событие всем подписчикам его темы.
+	Publish(event *Event) error
+	// Subscribe подписывает обработчик на тему. Обработчики одной подписки вызываются последовательно.
+	// Возвращаемая функция отменяет подписку.
+	Subscribe(topic string, handler func(*Event)) (func(), error)
+	Close() error
+}
+
+// Topic возвращает тему события этапа вида "stage.done".
+func Topic(stage, kind string) string { return stage + "." + kind }
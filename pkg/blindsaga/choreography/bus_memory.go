@@ -0,0 +1,91 @@
+package choreography
+
+import (
+	"fmt"
+	"licklib/threadsafe/condqueue"
+	"sync"
+)
+
+// memoryBus есть шина событий в пределах процесса.
+// Каждая подписка обладает собственной очередью и обработчиком, исполняющимся в отдельной горутине.
+type memoryBus struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[int]*condqueue.CondQueue[*Event] // Очереди подписок по темам.
+	capacity    int                                             // Ёмкость очереди каждой подписки.
+	next        int                                             // Идентификатор следующей подписки.
+	closed      bool
+}
+
+// NewMemoryBus создаёт шину событий в пределах процесса с заданной ёмкостью очереди каждой подписки.
+// При заполнении очереди публикация блокируется до её освобождения.
+func NewMemoryBus(capacity int) Bus { return newMemoryBus(capacity) }
+
+func newMemoryBus(capacity int) *memoryBus {
+	return &memoryBus{subscribers: map[string]map[int]*condqueue.CondQueue[*Event]{}, capacity: capacity}
+}
+
+func (slf *memoryBus) Publish(event *Event) error {
+	slf.mu.RLock()
+	if slf.closed {
+		slf.mu.RUnlock()
+		return fmt.Errorf("bus is closed")
+	}
+	queues := make([]*condqueue.CondQueue[*Event], 0, len(slf.subscribers[event.Topic]))
+	for _, v := range slf.subscribers[event.Topic] {
+		queues = append(queues, v)
+	}
+	slf.mu.RUnlock()
+
+	// Постановка в очередь производится без блокировки шины, поскольку обработчики сами могут публиковать события.
+	for _, v := range queues {
+		v.Enqueue(event)
+	}
+	return nil
+}
+
+func (slf *memoryBus) Subscribe(topic string, handler func(*Event)) (func(), error) {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if slf.closed {
+		return nil, fmt.Errorf("bus is closed")
+	}
+
+	id, queue := slf.next, condqueue.New[*Event](slf.capacity)
+	slf.next++
+	if slf.subscribers[topic] == nil {
+		slf.subscribers[topic] = map[int]*condqueue.CondQueue[*Event]{}
+	}
+	slf.subscribers[topic][id] = queue
+
+	go func() {
+		for {
+			event, ok := queue.Dequeue()
+			if !ok {
+				return
+			}
+			handler(event)
+		}
+	}()
+
+	return func() {
+		slf.mu.Lock()
+		defer slf.mu.Unlock()
+		delete(slf.subscribers[topic], id)
+		queue.Close()
+	}, nil
+}
+
+func (slf *memoryBus) Close() error {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if slf.closed {
+		return nil
+	}
+	slf.closed = true
+	for _, queues := range slf.subscribers {
+		for _, v := range queues {
+			v.Close()
+		}
+	}
+	return nil
+}
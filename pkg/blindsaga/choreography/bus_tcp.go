@@ -0,0 +1,144 @@
+package choreography
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"licklib/pkg/tcp/client"
+	"licklib/pkg/tcp/server"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	lineSize        = 1 << 20         // Максимальный размер сообщения брокера.
+	connectAttempts = 3               // Количество попыток подключения к брокеру.
+	writeTimeout    = 5 * time.Second // Время записи в соединение клиента, после которого брокер отключает клиента.
+)
+
+// tcpBroker пересылает каждое сообщение, полученное от клиента, всем подключённым клиентам, включая отправителя.
+type tcpBroker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewTCPBroker создаёт TCP-сервер, связывающий экземпляры шины, созданные через NewTCPBus.
+func NewTCPBroker(name, address string) (server.Server, error) {
+	broker := &tcpBroker{conns: map[net.Conn]struct{}{}}
+	return server.NewHandlerServer(name, address, broker.handle)
+}
+
+func (slf *tcpBroker) handle(ctx context.Context, conn net.Conn) {
+	slf.mu.Lock()
+	slf.conns[conn] = struct{}{}
+	slf.mu.Unlock()
+	defer func() {
+		slf.mu.Lock()
+		delete(slf.conns, conn)
+		slf.mu.Unlock()
+	}()
+
+	// При остановке сервера соединение закрывается, что прерывает чтение.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	scn := bufio.NewScanner(conn)
+	scn.Buffer(make([]byte, lineSize), lineSize)
+	for scn.Scan() {
+		slf.broadcast(append(slices.Clone(scn.Bytes()), '\n'))
+	}
+}
+
+// broadcast пишет сообщение в соединения вне блокировки, чтобы медленный клиент не останавливал остальных.
+// Клиент, не принявший сообщение за writeTimeout, отключается.
+func (slf *tcpBroker) broadcast(line []byte) {
+	slf.mu.Lock()
+	conns := make([]net.Conn, 0, len(slf.conns))
+	for conn := range slf.conns {
+		conns = append(conns, conn)
+	}
+	slf.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := conn.Write(line); err != nil {
+			conn.Close()
+		}
+	}
+}
+
+// tcpBus есть шина событий, публикующая события через TCP-брокер.
+// Полученные от брокера события доставляются локальным подписчикам так же, как в шине в пределах процесса.
+type tcpBus struct {
+	*memoryBus
+	client *client.Client
+
+	mu      sync.Mutex
+	closing bool
+	err     error // Причина потери соединения с брокером.
+}
+
+// NewTCPBus подключается к брокеру по адресу и создаёт шину событий с заданной ёмкостью очереди каждой подписки.
+func NewTCPBus(name, address string, capacity int) (Bus, error) {
+	c, err := client.NewClient(name, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Connect(connectAttempts); err != nil {
+		return nil, err
+	}
+	bus := &tcpBus{memoryBus: newMemoryBus(capacity), client: c}
+	go bus.read()
+	return bus, nil
+}
+
+func (slf *tcpBus) Publish(event *Event) error {
+	slf.mu.Lock()
+	err := slf.err
+	slf.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return slf.client.Send(string(data) + "\n")
+}
+
+// Close закрывает шину и возвращает ошибку, если соединение с брокером было потеряно раньше.
+func (slf *tcpBus) Close() error {
+	slf.mu.Lock()
+	slf.closing = true
+	err := slf.err
+	slf.mu.Unlock()
+	slf.client.Close()
+	slf.memoryBus.Close()
+	return err
+}
+
+// read доставляет события от брокера до закрытия соединения. При потере соединения шина закрывается,
+// чтобы подписчики не ожидали событий, которые уже не придут.
+func (slf *tcpBus) read() {
+	err := slf.client.ReadLines(slf.receive)
+	slf.mu.Lock()
+	if !slf.closing {
+		if err == nil {
+			err = fmt.Errorf("connection to broker is closed")
+		}
+		slf.err = err
+	}
+	slf.mu.Unlock()
+	slf.memoryBus.Close()
+}
+
+func (slf *tcpBus) receive(line []byte) {
+	event := &Event{}
+	if err := json.Unmarshal(line, event); err != nil {
+		return
+	}
+	slf.memoryBus.Publish(event)
+}
@@ -0,0 +1,216 @@
+package choreography
+
+import (
+	"context"
+	"fmt"
+	"licklib/pkg/blindsaga"
+	"maps"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Actions есть обработчики действия и отката этапа. Если обработчик отката равен nil, то откат считается пустым.
+type Actions struct {
+	Do, Undo blindsaga.ActionFunc
+}
+
+// Participant есть участник хореографической саги. Участнику известны только соседние этапы:
+// действие совершается по событию успеха предыдущего этапа, откат — по событию неудачи или отката следующего.
+// В отличие от оркестрации, центрального управления нет: каждый этап сам публикует события о своём результате.
+type Participant struct {
+	Name     string
+	Previous string // Имя предыдущего этапа; для первого этапа — имя инициатора.
+	Next     string // Имя следующего этапа; для последнего этапа пусто.
+	Actions
+}
+
+// Participants формирует участников по конфигурации саги в порядке следования этапов.
+// Для каждого этапа должны быть переданы обработчики; для этапов, не требующих отката, обработчик отката игнорируется.
+func Participants(config *blindsaga.Config, actions map[string]Actions) ([]*Participant, error) {
+	output := make([]*Participant, 0, len(config.Stages))
+	for i, v := range config.Stages {
+		a, ok := actions[v.Name]
+		if !ok || a.Do == nil {
+			return nil, fmt.Errorf("no actions for stage %v", v.Name)
+		}
+		if v.SkipUndo {
+			a.Undo = nil
+		}
+		p := &Participant{Name: v.Name, Previous: config.HostStage.Name, Actions: a}
+		if i > 0 {
+			p.Previous = config.Stages[i-1].Name
+		}
+		if i < len(config.Stages)-1 {
+			p.Next = config.Stages[i+1].Name
+		}
+		output = append(output, p)
+	}
+	return output, nil
+}
+
+// Join подписывает участника на события соседних этапов. Возвращаемая функция отменяет подписки.
+func (slf *Participant) Join(bus Bus) (func(), error) {
+	subscriptions := map[string]func(*Event){Topic(slf.Previous, KindDone): slf.do(bus)}
+	if slf.Next != "" {
+		subscriptions[Topic(slf.Next, KindFailed)] = slf.undo(bus)
+		subscriptions[Topic(slf.Next, KindUndone)] = slf.undo(bus)
+	}
+	return subscribe(bus, subscriptions)
+}
+
+func (slf *Participant) do(bus Bus) func(*Event) {
+	return func(event *Event) {
+		notification := clone(event.Notification)
+		meta, err := slf.Do(context.Background(), notification)
+		if len(meta) != 0 {
+			notification.Straight[slf.Name] = meta
+		}
+		// При возникновении ошибки сообщаем о неудаче, что запускает откат предыдущих этапов.
+		if err != nil {
+			notification.Action = blindsaga.ActionUndo
+			notification.FailedStage = &blindsaga.FailedStage{Stage: &blindsaga.Stage{Name: slf.Name}, Details: err.Error()}
+			notification.Undo = map[string][]byte{}
+			bus.Publish(&Event{Topic(slf.Name, KindFailed), notification})
+			return
+		}
+		bus.Publish(&Event{Topic(slf.Name, KindDone), notification})
+	}
+}
+
+func (slf *Participant) undo(bus Bus) func(*Event) {
+	return func(event *Event) {
+		notification := clone(event.Notification)
+		if slf.Undo != nil {
+			meta, err := slf.Undo(context.Background(), notification)
+			if err != nil {
+				meta = []byte(err.Error())
+			}
+			notification.Undo[slf.Name] = meta
+		}
+		bus.Publish(&Event{Topic(slf.Name, KindUndone), notification})
+	}
+}
+
+// Initiator запускает экземпляры хореографической саги и ожидает их завершения.
+// Сага завершается успехом по событию успеха последнего этапа и неудачей по событию неудачи или отката первого.
+type Initiator struct {
+	mu       sync.Mutex
+	bus      Bus
+	pending  map[string]chan *Event // Ожидающие завершения экземпляры по идентификаторам транзакций.
+	leave    func()                 // Отмена подписок инициатора.
+	host     string                 // Имя инициатора.
+	last     string                 // Имя последнего этапа.
+	sagaName string                 // Имя саги, переданное извне.
+	sagaID   string                 // Уникальный идентификатор саги.
+}
+
+// NewInitiator создаёт инициатора саги по конфигурации.
+func NewInitiator(bus Bus, config *blindsaga.Config) (*Initiator, error) {
+	if len(config.Stages) == 0 {
+		return nil, fmt.Errorf("empty saga")
+	}
+	first, last := config.Stages[0].Name, config.Stages[len(config.Stages)-1].Name
+	initiator := &Initiator{
+		bus:      bus,
+		pending:  map[string]chan *Event{},
+		host:     config.HostStage.Name,
+		last:     last,
+		sagaName: config.SagaName,
+		sagaID:   uuid.NewString(),
+	}
+	leave, err := subscribe(bus, map[string]func(*Event){
+		Topic(last, KindDone):    initiator.complete,
+		Topic(first, KindFailed): initiator.complete,
+		Topic(first, KindUndone): initiator.complete,
+	})
+	if err != nil {
+		return nil, err
+	}
+	initiator.leave = leave
+	return initiator, nil
+}
+
+// ID возвращает уникальный идентификатор саги.
+func (slf *Initiator) ID() string { return slf.sagaID }
+
+// Start запускает экземпляр саги с некоторой начальной информацией и дожидается его завершения.
+// Возвращает накопленную метаинформацию и флаг успеха; ошибка возвращается, если завершения дождаться не удалось.
+func (slf *Initiator) Start(ctx context.Context, meta []byte) (*blindsaga.Meta, bool, error) {
+	notification := &blindsaga.Notification{
+		SagaName:      slf.sagaName,
+		SagaID:        slf.sagaID,
+		TransactionID: uuid.NewString(),
+		Action:        blindsaga.ActionDo,
+		Meta:          &blindsaga.Meta{Straight: map[string][]byte{slf.host: meta}},
+	}
+	result := make(chan *Event, 1)
+	slf.mu.Lock()
+	slf.pending[notification.TransactionID] = result
+	slf.mu.Unlock()
+	defer func() {
+		slf.mu.Lock()
+		delete(slf.pending, notification.TransactionID)
+		slf.mu.Unlock()
+	}()
+
+	// Успех инициатора есть сигнал к началу действия первого этапа.
+	if err := slf.bus.Publish(&Event{Topic(slf.host, KindDone), notification}); err != nil {
+		return nil, false, err
+	}
+	select {
+	case event := <-result:
+		return event.Notification.Meta, event.Topic == Topic(slf.last, KindDone), nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// Close отменяет подписки инициатора.
+func (slf *Initiator) Close() { slf.leave() }
+
+func (slf *Initiator) complete(event *Event) {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if result, ok := slf.pending[event.Notification.TransactionID]; ok {
+		result <- event
+		delete(slf.pending, event.Notification.TransactionID)
+	}
+}
+
+// subscribe подписывает обработчики на темы. При ошибке уже оформленные подписки отменяются.
+func subscribe(bus Bus, subscriptions map[string]func(*Event)) (func(), error) {
+	unsubscribes := []func(){}
+	leave := func() {
+		for _, v := range unsubscribes {
+			v()
+		}
+	}
+	for topic, handler := range subscriptions {
+		unsubscribe, err := bus.Subscribe(topic, handler)
+		if err != nil {
+			leave()
+			return nil, err
+		}
+		unsubscribes = append(unsubscribes, unsubscribe)
+	}
+	return leave, nil
+}
+
+// clone копирует уведомление, чтобы обработчики разных подписок не изменяли общие данные.
+func clone(notification *blindsaga.Notification) *blindsaga.Notification {
+	output := *notification
+	meta := blindsaga.Meta{}
+	if notification.Meta != nil {
+		meta = *notification.Meta
+	}
+	meta.Straight, meta.Undo = maps.Clone(meta.Straight), maps.Clone(meta.Undo)
+	if meta.Straight == nil {
+		meta.Straight = map[string][]byte{}
+	}
+	if meta.Undo == nil && notification.Action == blindsaga.ActionUndo {
+		meta.Undo = map[string][]byte{}
+	}
+	output.Meta = &meta
+	return &output
+}
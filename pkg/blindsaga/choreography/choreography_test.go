@@ -0,0 +1,107 @@
+package choreography
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"licklib/pkg/blindsaga"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const hostName = "host"
+
+func TestChoreography(t *testing.T) {
+	testFlow := func(t *testing.T, bus Bus, failure bool) {
+		config := &blindsaga.Config{HostStage: blindsaga.Stage{Name: hostName}, SagaName: "choreography"}
+		var counter atomic.Int64
+		actions := map[string]Actions{}
+		for i := range 5 {
+			name := fmt.Sprint("stage", i)
+			config.Stages = append(config.Stages, blindsaga.Stage{Name: name})
+			actions[name] = Actions{
+				Do: func(_ context.Context, n *blindsaga.Notification) ([]byte, error) {
+					// Каждый этап убеждается, что получил данные инициатора.
+					assert.Equal(t, []byte("meta"), n.Straight[hostName])
+					if failure && i == 4 {
+						return nil, errors.New("failure")
+					}
+					counter.Add(1)
+					return fmt.Append(nil, i), nil
+				},
+				Undo: func(context.Context, *blindsaga.Notification) ([]byte, error) {
+					counter.Add(-1)
+					return fmt.Append(nil, -i), nil
+				},
+			}
+		}
+
+		participants, err := Participants(config, actions)
+		assert.NoError(t, err)
+		for _, v := range participants {
+			leave, err := v.Join(bus)
+			assert.NoError(t, err)
+			defer leave()
+		}
+		initiator, err := NewInitiator(bus, config)
+		assert.NoError(t, err)
+		defer initiator.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		meta, ok, err := initiator.Start(ctx, []byte("meta"))
+		assert.NoError(t, err)
+		if failure {
+			assert.False(t, ok)
+			assert.Zero(t, counter.Load())
+			assert.Equal(t, "stage4", meta.FailedStage.Name)
+			assert.Len(t, meta.Undo, 4)
+			assert.Equal(t, []byte("-3"), meta.Undo["stage3"])
+		} else {
+			assert.True(t, ok)
+			assert.Equal(t, int64(5), counter.Load())
+			assert.Nil(t, meta.FailedStage)
+			assert.Len(t, meta.Straight, 6)
+			assert.Equal(t, []byte("4"), meta.Straight["stage4"])
+		}
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		for _, failure := range []bool{false, true} {
+			bus := NewMemoryBus(16)
+			testFlow(t, bus, failure)
+			assert.NoError(t, bus.Close())
+		}
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		// Получаем свободный адрес для брокера.
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		address := l.Addr().String()
+		assert.NoError(t, l.Close())
+
+		broker, err := NewTCPBroker("broker", address)
+		assert.NoError(t, err)
+		go broker.Serve()
+		defer broker.Close()
+
+		for _, failure := range []bool{false, true} {
+			bus, err := NewTCPBus("bus", address, 16)
+			assert.NoError(t, err)
+			testFlow(t, bus, failure)
+			assert.NoError(t, bus.Close())
+		}
+
+		// При остановке брокера шина сообщает о потере соединения.
+		bus, err := NewTCPBus("bus", address, 16)
+		assert.NoError(t, err)
+		broker.Close()
+		assert.Eventually(t, func() bool { return bus.Publish(&Event{Topic: "topic"}) != nil }, 5*time.Second, 10*time.Millisecond)
+		assert.Error(t, bus.Close())
+	})
+}
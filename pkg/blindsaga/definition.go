@@ -0,0 +1,224 @@
+package blindsaga
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefinitionError есть ошибка описания саги с указанием строки файла, к которой она относится.
+type DefinitionError struct {
+	Line    int
+	Message string
+}
+
+func (slf *DefinitionError) Error() string { return fmt.Sprintf("line %v: %v", slf.Line, slf.Message) }
+
+// sagaDefinition есть декларативное описание саги.
+type sagaDefinition struct {
+	Name                 string            `yaml:"name"`
+	Host                 hostDefinition    `yaml:"host"`
+	Deadline             duration          `yaml:"deadline"`
+	CompensationDeadline duration          `yaml:"compensation_deadline"`
+	Defaults             policyDefinition  `yaml:"defaults"`
+	Stages               []stageDefinition `yaml:"stages"`
+}
+
+// hostDefinition есть описание оркестратора.
+type hostDefinition struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address"`
+}
+
+// policyDefinition есть описание политик исполнения этапа.
+// Незаданные поля этапа наследуются из политик по умолчанию.
+type policyDefinition struct {
+	Timeout   *duration        `yaml:"timeout"`
+	Retry     *retryDefinition `yaml:"retry"`
+	UndoRetry *retryDefinition `yaml:"undo_retry"`
+	SkipUndo  *bool            `yaml:"skip_undo"`
+}
+
+// stageDefinition есть описание этапа саги.
+type stageDefinition struct {
+	Name             string `yaml:"name"`
+	Address          string `yaml:"address"`
+	Kind             string `yaml:"kind"`
+	policyDefinition `yaml:",inline"`
+}
+
+// retryDefinition есть описание политики повтора.
+type retryDefinition struct {
+	Attempts int      `yaml:"attempts"`
+	Backoff  duration `yaml:"backoff"`
+}
+
+// duration есть промежуток времени, записываемый в описании строкой вида "1m30s".
+type duration time.Duration
+
+func (slf *duration) UnmarshalYAML(node *yaml.Node) error {
+	d, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return &DefinitionError{node.Line, fmt.Sprintf("invalid duration %q", node.Value)}
+	}
+	if d < 0 {
+		return &DefinitionError{node.Line, fmt.Sprintf("negative duration %q", node.Value)}
+	}
+	*slf = duration(d)
+	return nil
+}
+
+// LoadConfig читает описание саги из файла формата YAML или JSON и формирует по нему конфигурацию.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig формирует конфигурацию саги по описанию формата YAML или JSON.
+// Ошибки описания возвращаются с указанием строки, к которой они относятся.
+func ParseConfig(data []byte) (*Config, error) {
+	// Дерево документа используется только для определения строк, к которым относятся ошибки.
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return nil, err
+	}
+
+	definition := &sagaDefinition{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(definition); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &DefinitionError{1, "empty definition"}
+		}
+		return nil, err
+	}
+
+	if definition.Host.Name == "" {
+		return nil, &DefinitionError{position(root, "host"), "empty host name"}
+	}
+	if len(definition.Stages) == 0 {
+		return nil, &DefinitionError{position(root, "stages"), "empty saga"}
+	}
+	if err := definition.Defaults.validate(root, "defaults"); err != nil {
+		return nil, err
+	}
+
+	config := &Config{
+		SagaName:             definition.Name,
+		HostStage:            Stage{Name: definition.Host.Name, Address: definition.Host.Address},
+		Deadline:             time.Duration(definition.Deadline),
+		CompensationDeadline: time.Duration(definition.CompensationDeadline),
+	}
+	uniqueCheck := map[string]struct{}{}
+	for i, v := range definition.Stages {
+		if v.Name == "" {
+			return nil, &DefinitionError{position(root, "stages", i), "empty stage name"}
+		}
+		if _, ok := uniqueCheck[v.Name]; ok {
+			return nil, &DefinitionError{position(root, "stages", i, "name"), fmt.Sprintf("non-unique stage name %v", v.Name)}
+		}
+		uniqueCheck[v.Name] = struct{}{}
+		if u, err := url.Parse(v.Address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, &DefinitionError{position(root, "stages", i, "address"), fmt.Sprintf("invalid address %q of stage %v", v.Address, v.Name)}
+		}
+		if err := v.validate(root, "stages", i); err != nil {
+			return nil, err
+		}
+		config.Stages = append(config.Stages, v.stage(&definition.Defaults))
+	}
+	if i, err := validateKinds(config.Stages); err != nil {
+		return nil, &DefinitionError{position(root, "stages", i, "kind"), err.Error()}
+	}
+	return config, nil
+}
+
+// validate проверяет корректность политик, расположенных в документе по заданному пути.
+func (slf *policyDefinition) validate(root *yaml.Node, path ...any) error {
+	if slf.Retry != nil && slf.Retry.Attempts < 0 {
+		return &DefinitionError{position(root, append(path, "retry", "attempts")...), "negative number of attempts"}
+	}
+	if slf.UndoRetry != nil && slf.UndoRetry.Attempts < 0 {
+		return &DefinitionError{position(root, append(path, "undo_retry", "attempts")...), "negative number of attempts"}
+	}
+	return nil
+}
+
+// stage формирует этап саги, дополняя незаданные политики значениями по умолчанию.
+func (slf *stageDefinition) stage(defaults *policyDefinition) Stage {
+	policy := slf.policyDefinition
+	if policy.Timeout == nil {
+		policy.Timeout = defaults.Timeout
+	}
+	if policy.Retry == nil {
+		policy.Retry = defaults.Retry
+	}
+	if policy.UndoRetry == nil {
+		policy.UndoRetry = defaults.UndoRetry
+	}
+	if policy.SkipUndo == nil {
+		policy.SkipUndo = defaults.SkipUndo
+	}
+
+	stage := Stage{Name: slf.Name, Address: slf.Address, Kind: slf.Kind}
+	if policy.Timeout != nil {
+		stage.Timeout = time.Duration(*policy.Timeout)
+	}
+	if policy.Retry != nil {
+		stage.Retry = Retry{policy.Retry.Attempts, time.Duration(policy.Retry.Backoff)}
+	}
+	if policy.UndoRetry != nil {
+		stage.UndoRetry = Retry{policy.UndoRetry.Attempts, time.Duration(policy.UndoRetry.Backoff)}
+	}
+	if policy.SkipUndo != nil {
+		stage.SkipUndo = *policy.SkipUndo
+	}
+	return stage
+}
+
+// position возвращает номер строки узла, расположенного в документе по пути из ключей и индексов.
+// Если путь не может быть пройден полностью, возвращается строка последнего найденного узла.
+func position(root *yaml.Node, path ...any) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) != 0 {
+		node = node.Content[0]
+	}
+	for _, step := range path {
+		var next *yaml.Node
+		switch step := step.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == step {
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && step < len(node.Content) {
+				next = node.Content[step]
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	if node.Line == 0 {
+		return 1
+	}
+	return node.Line
+}
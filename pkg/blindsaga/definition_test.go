@@ -0,0 +1,87 @@
+package blindsaga
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		config, err := ParseConfig([]byte(`
+name: order
+host:
+  name: orchestrator
+deadline: 1m
+compensation_deadline: 30s
+defaults:
+  timeout: 5s
+  retry: {attempts: 3, backoff: 100ms}
+stages:
+  - name: reserve
+    address: http://localhost:8001/reserve
+  - name: charge
+    address: http://localhost:8002/charge
+    timeout: 1s
+    undo_retry: {attempts: 5, backoff: 1s}
+  - name: notify
+    address: https://localhost:8003/notify
+    kind: retriable
+    skip_undo: true
+`))
+		assert.NoError(t, err)
+		assert.Equal(t, "order", config.SagaName)
+		assert.Equal(t, "orchestrator", config.HostStage.Name)
+		assert.Equal(t, time.Minute, config.Deadline)
+		assert.Equal(t, 30*time.Second, config.CompensationDeadline)
+		assert.Equal(t, []Stage{
+			{Name: "reserve", Address: "http://localhost:8001/reserve", Timeout: 5 * time.Second, Retry: Retry{3, 100 * time.Millisecond}},
+			{Name: "charge", Address: "http://localhost:8002/charge", Timeout: time.Second, Retry: Retry{3, 100 * time.Millisecond}, UndoRetry: Retry{5, time.Second}},
+			{Name: "notify", Address: "https://localhost:8003/notify", Kind: KindRetriable, Timeout: 5 * time.Second, Retry: Retry{3, 100 * time.Millisecond}, SkipUndo: true},
+		}, config.Stages)
+
+		// Полученная конфигурация пригодна для создания саги.
+		_, err = New(config, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "saga.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{
+  "name": "order",
+  "host": {"name": "orchestrator"},
+  "stages": [
+    {"name": "reserve", "address": "http://localhost:8001", "retry": {"attempts": 2, "backoff": "1s"}}
+  ]
+}`), 0o644))
+		config, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []Stage{{Name: "reserve", Address: "http://localhost:8001", Retry: Retry{2, time.Second}}}, config.Stages)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for definition, line := range map[string]int{
+			"name: order\nstages: []\n":     1,
+			"host: {name: h}\nstages: []\n": 2,
+			"host: {name: h}\nstages:\n  - name: a\n    address: http://a\n  - name: a\n    address: http://b\n":          5,
+			"host: {name: h}\nstages:\n  - name: a\n    address: ftp://a\n":                                               4,
+			"host: {name: h}\nstages:\n  - name: a\n    address: http://a\n    timeout: soon\n":                           5,
+			"host: {name: h}\ndefaults:\n  retry:\n    attempts: -1\nstages:\n  - {name: a, address: http://a}\n":         4,
+			"host: {name: h}\nstages:\n  - {name: a, address: http://a, kind: pivot}\n  - {name: b, address: http://b}\n": 4,
+			"host: {name: h}\nstages:\n  - name: a\n    address: http://a\n    kind: pivotal\n":                           5,
+		} {
+			_, err := ParseConfig([]byte(definition))
+			definitionErr := &DefinitionError{}
+			if assert.ErrorAs(t, err, &definitionErr, definition) {
+				assert.Equal(t, line, definitionErr.Line, definition)
+			}
+		}
+
+		// Неизвестные поля отвергаются с указанием строки средствами декодера.
+		_, err := ParseConfig([]byte("host: {name: h}\nstages:\n  - name: a\n    adress: http://a\n"))
+		assert.ErrorContains(t, err, "line 4")
+	})
+}
@@ -1,17 +1,30 @@
 package blindsaga
 
+import "time"
+
 const (
 	ct = "application/json"
 
 	ActionDo   = "DO"
 	ActionUndo = "UNDO"
+
+	KindCompensatable = "compensatable" // Этап, действие которого может быть откатано. Вид этапа по умолчанию.
+	KindPivot         = "pivot"         // Поворотный этап: после его успеха сага может двигаться только вперёд.
+	KindRetriable     = "retriable"     // Этап после поворотного: не откатывается, а повторяется до успеха или постоянной ошибки.
+
+	StatusRunning   = "RUNNING"   // Сага исполняется.
+	StatusSucceeded = "SUCCEEDED" // Все этапы саги выполнены.
+	StatusFailed    = "FAILED"    // Сага остановлена, состояние всех этапов известно.
+	StatusUnknown   = "UNKNOWN"   // Сага остановлена по истечении времени, состояние части этапов неизвестно.
 )
 
 // Config есть конфигурация саги, необходимая для инициализации.
 type Config struct {
-	Stages    []Stage
-	HostStage Stage
-	SagaName  string
+	Stages               []Stage
+	HostStage            Stage
+	SagaName             string
+	Deadline             time.Duration // Ограничение времени прямого прохождения; 0 — без ограничения.
+	CompensationDeadline time.Duration // Ограничение времени отката; 0 — без ограничения.
 }
 
 // Notification есть уведомление, доставляемое участникам саги.
@@ -28,6 +41,8 @@ type Meta struct {
 	Straight    map[string][]byte // Хранилище, заполняемое при прямом прохождении.
 	Undo        map[string][]byte // Хранилище, заполняемое при обратном прохождении.
 	FailedStage *FailedStage      // Информация об этапе, на котором случиласб ошибка.
+	Status      string            `json:",omitempty"` // Итоговый статус саги.
+	Unknown     []string          `json:",omitempty"` // Этапы, состояние которых неизвестно из-за истечения времени.
 }
 
 // Failed представляет этап, завершившийся неудачей.
@@ -37,7 +52,19 @@ type FailedStage struct {
 }
 
 // Stage представляет этап саги.
+// Политики исполнения этапа известны только оркестратору и участникам не пересылаются.
 type Stage struct {
-	Name    string
-	Address string
+	Name      string
+	Address   string
+	Kind      string        `json:"-"` // Вид этапа; пустое значение соответствует KindCompensatable.
+	Timeout   time.Duration `json:"-"` // Ограничение времени одного запроса к этапу; 0 — без ограничения.
+	Retry     Retry         `json:"-"` // Политика повтора прямого действия.
+	UndoRetry Retry         `json:"-"` // Политика повтора отката.
+	SkipUndo  bool          `json:"-"` // Флаг того, что этап не требует уведомления об откате.
+}
+
+// Retry есть политика повтора запросов к этапу.
+type Retry struct {
+	Attempts int           // Общее количество попыток; 0 и 1 означают единственную попытку.
+	Backoff  time.Duration // Пауза между попытками.
 }
@@ -0,0 +1,185 @@
+package blindsaga
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Step есть запись о единичной попытке уведомления этапа.
+type Step struct {
+	Stage      string
+	Action     string
+	Attempt    int
+	Start      time.Time
+	End        time.Time
+	StatusCode int    `json:",omitempty"` // Код ответа этапа; 0, если ответ не был получен.
+	Error      string `json:",omitempty"`
+}
+
+// Record есть история исполнения экземпляра саги.
+type Record struct {
+	SagaName      string
+	SagaID        string
+	TransactionID string
+	Status        string
+	Start         time.Time
+	End           time.Time
+	Steps         []Step
+}
+
+// History есть хранилище истории исполнения саг.
+// Одно хранилище может использоваться несколькими сагами одновременно.
+type History struct {
+	mu         sync.RWMutex
+	records    map[string]*Record // Записи по идентификаторам транзакций.
+	order      []string           // Идентификаторы транзакций в порядке запуска.
+	maxRecords int
+}
+
+// HistoryOption предназначен для настройки хранилища истории в конструкторе.
+type HistoryOption func(*History)
+
+// WithMaxRecords ограничивает число хранимых историй транзакций.
+// При превышении удаляются истории самых старых завершённых транзакций; истории исполняющихся не удаляются.
+func WithMaxRecords(n int) HistoryOption {
+	return func(h *History) { h.maxRecords = n }
+}
+
+// NewHistory создаёт пустое хранилище истории. По умолчанию число историй не ограничено.
+func NewHistory(options ...HistoryOption) *History {
+	history := &History{records: map[string]*Record{}}
+	for _, option := range options {
+		option(history)
+	}
+	return history
+}
+
+// Transaction возвращает копию истории транзакции по её идентификатору.
+func (slf *History) Transaction(transactionID string) (*Record, bool) {
+	slf.mu.RLock()
+	defer slf.mu.RUnlock()
+	record, ok := slf.records[transactionID]
+	if !ok {
+		return nil, false
+	}
+	return record.copy(), true
+}
+
+// Saga возвращает копии историй всех транзакций саги в порядке запуска.
+func (slf *History) Saga(sagaID string) []*Record {
+	return slf.filter(func(r *Record) bool { return r.SagaID == sagaID })
+}
+
+// Running возвращает копии историй исполняющихся транзакций в порядке запуска.
+func (slf *History) Running() []*Record {
+	return slf.filter(func(r *Record) bool { return r.Status == StatusRunning })
+}
+
+// ServeHTTP отдаёт историю в формате JSON. Поддерживаются параметры запроса:
+// transaction_id — история одной транзакции, saga_id — истории всех транзакций саги.
+// Без параметров отдаются истории исполняющихся транзакций.
+func (slf *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var output any
+	query := r.URL.Query()
+	switch {
+	case query.Has("transaction_id"):
+		record, ok := slf.Transaction(query.Get("transaction_id"))
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		output = record
+	case query.Has("saga_id"):
+		output = slf.Saga(query.Get("saga_id"))
+	default:
+		output = slf.Running()
+	}
+
+	w.Header().Set("Content-Type", ct)
+	json.NewEncoder(w).Encode(output)
+}
+
+// begin заводит историю транзакции по уведомлению о её запуске.
+func (slf *History) begin(notification *Notification) {
+	if slf == nil {
+		return
+	}
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	slf.records[notification.TransactionID] = &Record{
+		SagaName:      notification.SagaName,
+		SagaID:        notification.SagaID,
+		TransactionID: notification.TransactionID,
+		Status:        StatusRunning,
+		Start:         time.Now().UTC(),
+		Steps:         []Step{},
+	}
+	slf.order = append(slf.order, notification.TransactionID)
+	slf.evict()
+}
+
+// step добавляет запись о попытке уведомления этапа в историю транзакции.
+func (slf *History) step(transactionID string, step Step) {
+	if slf == nil {
+		return
+	}
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if record, ok := slf.records[transactionID]; ok {
+		record.Steps = append(record.Steps, step)
+	}
+}
+
+// finish фиксирует итоговый статус транзакции.
+func (slf *History) finish(transactionID, status string) {
+	if slf == nil {
+		return
+	}
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if record, ok := slf.records[transactionID]; ok {
+		record.Status, record.End = status, time.Now().UTC()
+	}
+}
+
+// evict удаляет истории самых старых завершённых транзакций сверх ограничения. Вызывается под блокировкой.
+func (slf *History) evict() {
+	excess := len(slf.order) - slf.maxRecords
+	if slf.maxRecords <= 0 || excess <= 0 {
+		return
+	}
+	slf.order = slices.DeleteFunc(slf.order, func(id string) bool {
+		if excess > 0 && slf.records[id].Status != StatusRunning {
+			delete(slf.records, id)
+			excess--
+			return true
+		}
+		return false
+	})
+}
+
+func (slf *History) filter(f func(*Record) bool) []*Record {
+	slf.mu.RLock()
+	defer slf.mu.RUnlock()
+	output := []*Record{}
+	for _, id := range slf.order {
+		if record := slf.records[id]; f(record) {
+			output = append(output, record.copy())
+		}
+	}
+	return output
+}
+
+func (slf *Record) copy() *Record {
+	output := *slf
+	output.Steps = slices.Clone(slf.Steps)
+	return &output
+}
@@ -0,0 +1,88 @@
+package blindsaga
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	config := &Config{HostStage: Stage{Name: hostName}, SagaName: uuid.NewString()}
+	counter := &struct{ n int }{}
+	for i := range 3 {
+		server := &testServer{t: t, _ID: uuid.NewString(), counter: counter, willFail: i == 1}
+		server.server = httptest.NewServer(server)
+		defer server.server.Close()
+		config.Stages = append(config.Stages, Stage{Name: server._ID, Address: server.server.URL, Retry: Retry{Attempts: 2}})
+	}
+
+	history := NewHistory()
+	s, err := New(config, nil, WithHistory(history))
+	assert.NoError(t, err)
+	_, ok := s.Start(fmt.Append(nil, 1))
+	assert.False(t, ok)
+	_, ok = s.Start(fmt.Append(nil, 2))
+	assert.False(t, ok)
+
+	records := history.Saga(s.ID())
+	assert.Len(t, records, 2)
+	assert.Empty(t, history.Running())
+
+	record, ok := history.Transaction(records[0].TransactionID)
+	assert.True(t, ok)
+	assert.Equal(t, StatusFailed, record.Status)
+	assert.Equal(t, config.SagaName, record.SagaName)
+	assert.False(t, record.End.Before(record.Start))
+
+	// Первый этап выполнен, второй дважды завершился ошибкой, после чего первый этап откатан.
+	expected := []Step{
+		{Stage: config.Stages[0].Name, Action: ActionDo, Attempt: 1, StatusCode: http.StatusOK},
+		{Stage: config.Stages[1].Name, Action: ActionDo, Attempt: 1, StatusCode: http.StatusInternalServerError, Error: "unexpected status code 500"},
+		{Stage: config.Stages[1].Name, Action: ActionDo, Attempt: 2, StatusCode: http.StatusInternalServerError, Error: "unexpected status code 500"},
+		{Stage: config.Stages[0].Name, Action: ActionUndo, Attempt: 1, StatusCode: http.StatusOK},
+	}
+	if assert.Len(t, record.Steps, len(expected)) {
+		for i, step := range record.Steps {
+			assert.False(t, step.End.Before(step.Start))
+			step.Start, step.End = expected[i].Start, expected[i].End
+			assert.Equal(t, expected[i], step)
+		}
+	}
+
+	// История доступна по HTTP.
+	w := httptest.NewRecorder()
+	history.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?saga_id="+s.ID(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	served := []*Record{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Len(t, served, 2)
+	assert.Equal(t, records[1].TransactionID, served[1].TransactionID)
+
+	w = httptest.NewRecorder()
+	history.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?transaction_id=unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHistoryMaxRecords(t *testing.T) {
+	history := NewHistory(WithMaxRecords(2))
+	for i := range 4 {
+		history.begin(&Notification{SagaID: "saga", TransactionID: fmt.Sprint(i)})
+		if i != 1 {
+			history.finish(fmt.Sprint(i), StatusSucceeded)
+		}
+	}
+
+	// Вытеснены самые старые завершённые истории; история исполняющейся транзакции сохранена.
+	ids := []string{}
+	for _, record := range history.Saga("saga") {
+		ids = append(ids, record.TransactionID)
+	}
+	assert.Equal(t, []string{"1", "3"}, ids)
+	_, ok := history.Transaction("0")
+	assert.False(t, ok)
+}
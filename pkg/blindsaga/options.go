@@ -0,0 +1,9 @@
+package blindsaga
+
+// Option предназначен для настройки саги в конструкторе.
+type Option func(*BlindSaga)
+
+// WithHistory подключает хранилище, в которое сага записывает историю исполнения своих экземпляров.
+func WithHistory(history *History) Option {
+	return func(s *BlindSaga) { s.history = history }
+}
@@ -0,0 +1,175 @@
+package blindsaga
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// ActionFunc есть обработчик действия или отката на стороне участника саги.
+// Возвращаемые данные передаются оркестратору как метаинформация этапа.
+type ActionFunc func(ctx context.Context, notification *Notification) ([]byte, error)
+
+// Participant есть участник саги: принимает HTTP-уведомления оркестратора и вызывает обработчики действия и отката.
+// Повторное уведомление о той же транзакции не приводит к повторному вызову обработчика, а возвращает прежний результат.
+// Откат действия, которое не было совершено, не производит никаких действий.
+type Participant struct {
+	mu              sync.Mutex
+	do, undo        ActionFunc              // Обработчики действия и отката.
+	transactions    map[string]*transaction // Состояние транзакций по их идентификаторам.
+	order           []string                // Идентификаторы транзакций в порядке первого уведомления.
+	maxTransactions int                     // Предельное число хранимых состояний; 0 — без ограничения.
+}
+
+// transaction есть состояние транзакции на стороне участника.
+type transaction struct {
+	mu               sync.Mutex
+	refs             int    // Число обрабатываемых уведомлений о транзакции; изменяется под блокировкой участника.
+	done, undone     bool   // Флаги совершения действия и отката.
+	doMeta, undoMeta []byte // Результаты обработчиков.
+}
+
+// ParticipantOption предназначен для настройки участника саги в конструкторе.
+type ParticipantOption func(*Participant)
+
+// WithMaxTransactions ограничивает число хранимых состояний транзакций.
+// При превышении удаляются состояния самых старых транзакций, уведомления о которых не обрабатываются.
+// Уведомление об удалённой транзакции обрабатывается как новое, поэтому предел следует выбирать
+// с запасом на транзакции, которые ещё могут быть откатаны.
+func WithMaxTransactions(n int) ParticipantOption {
+	return func(p *Participant) { p.maxTransactions = n }
+}
+
+// NewParticipant создаёт участника саги. Если обработчик отката равен nil, то откат считается пустым.
+// По умолчанию число хранимых состояний транзакций не ограничено.
+func NewParticipant(do, undo ActionFunc, options ...ParticipantOption) *Participant {
+	if undo == nil {
+		undo = func(context.Context, *Notification) ([]byte, error) { return nil, nil }
+	}
+	participant := &Participant{do: do, undo: undo, transactions: map[string]*transaction{}}
+	for _, option := range options {
+		option(participant)
+	}
+	return participant
+}
+
+func (slf *Participant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	notification := &Notification{}
+	if err := json.NewDecoder(r.Body).Decode(notification); err != nil || notification.TransactionID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if notification.Meta == nil {
+		notification.Meta = &Meta{}
+	}
+
+	// Уведомления об одной транзакции обрабатываются последовательно.
+	t := slf.acquire(notification.TransactionID)
+	defer slf.release(t)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch notification.Action {
+	case ActionDo:
+		switch {
+		case t.undone:
+			// Запоздалое действие после отката не совершается.
+			w.WriteHeader(http.StatusConflict)
+		case t.done:
+			w.Write(t.doMeta)
+		default:
+			meta, err := slf.do(r.Context(), notification)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			t.done, t.doMeta = true, meta
+			w.Write(meta)
+		}
+	case ActionUndo:
+		switch {
+		case t.undone:
+			w.Write(t.undoMeta)
+		case !t.done:
+			// Действие не совершалось, откатывать нечего.
+			t.undone = true
+		default:
+			meta, err := slf.undo(r.Context(), notification)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			t.undone, t.undoMeta = true, meta
+			w.Write(meta)
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Completed сообщает, были ли в рамках транзакции завершены действие и откат.
+func (slf *Participant) Completed(transactionID string) (done, undone bool) {
+	slf.mu.Lock()
+	t, ok := slf.transactions[transactionID]
+	slf.mu.Unlock()
+	if !ok {
+		return false, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.done, t.undone
+}
+
+// Forget удаляет сведения о транзакции. Последующие уведомления о ней будут обработаны как новые.
+func (slf *Participant) Forget(transactionID string) {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if _, ok := slf.transactions[transactionID]; ok {
+		delete(slf.transactions, transactionID)
+		slf.order = slices.DeleteFunc(slf.order, func(id string) bool { return id == transactionID })
+	}
+}
+
+// acquire возвращает состояние транзакции, создавая его при необходимости, и отмечает его обрабатываемым.
+func (slf *Participant) acquire(id string) *transaction {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	t, ok := slf.transactions[id]
+	if !ok {
+		t = &transaction{}
+		slf.transactions[id] = t
+		slf.order = append(slf.order, id)
+		slf.evict()
+	}
+	t.refs++
+	return t
+}
+
+// release снимает отметку об обработке уведомления о транзакции.
+func (slf *Participant) release(t *transaction) {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	t.refs--
+}
+
+// evict удаляет состояния самых старых необрабатываемых транзакций сверх предела. Вызывается под блокировкой.
+func (slf *Participant) evict() {
+	excess := len(slf.order) - slf.maxTransactions
+	if slf.maxTransactions <= 0 || excess <= 0 {
+		return
+	}
+	slf.order = slices.DeleteFunc(slf.order, func(id string) bool {
+		if excess > 0 && slf.transactions[id].refs == 0 {
+			delete(slf.transactions, id)
+			excess--
+			return true
+		}
+		return false
+	})
+}
@@ -0,0 +1,127 @@
+package blindsaga
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParticipant(t *testing.T) {
+	var balance int
+	participant := NewParticipant(
+		func(_ context.Context, n *Notification) ([]byte, error) {
+			balance += 10
+			return []byte("charged"), nil
+		},
+		func(_ context.Context, n *Notification) ([]byte, error) {
+			balance -= 10
+			return []byte("refunded"), nil
+		},
+	)
+	failing := NewParticipant(
+		func(context.Context, *Notification) ([]byte, error) { return nil, errors.New("out of stock") },
+		nil,
+	)
+	server, failingServer := httptest.NewServer(participant), httptest.NewServer(failing)
+	defer server.Close()
+	defer failingServer.Close()
+
+	notify := func(action, transactionID string) (int, string) {
+		body, err := json.Marshal(&Notification{TransactionID: transactionID, Action: action, Meta: &Meta{}})
+		assert.NoError(t, err)
+		res, err := http.Post(server.URL, ct, bytes.NewReader(body))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		meta, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		return res.StatusCode, string(meta)
+	}
+
+	t.Run("dedupe", func(t *testing.T) {
+		for range 3 {
+			code, meta := notify(ActionDo, "t1")
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, "charged", meta)
+		}
+		assert.Equal(t, 10, balance)
+		for range 3 {
+			code, meta := notify(ActionUndo, "t1")
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, "refunded", meta)
+		}
+		assert.Zero(t, balance)
+		done, undone := participant.Completed("t1")
+		assert.True(t, done)
+		assert.True(t, undone)
+	})
+
+	t.Run("undo without do", func(t *testing.T) {
+		code, meta := notify(ActionUndo, "t2")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, meta)
+		assert.Zero(t, balance)
+
+		// Запоздалое действие после отката отвергается.
+		code, _ = notify(ActionDo, "t2")
+		assert.Equal(t, http.StatusConflict, code)
+		assert.Zero(t, balance)
+	})
+
+	t.Run("saga", func(t *testing.T) {
+		s, err := New(&Config{
+			HostStage: Stage{Name: hostName},
+			Stages:    []Stage{{Name: "charge", Address: server.URL}, {Name: "reserve", Address: failingServer.URL}},
+		}, nil)
+		assert.NoError(t, err)
+		meta, ok := s.Start(nil)
+		assert.False(t, ok)
+		assert.Equal(t, "reserve", meta.FailedStage.Name)
+		assert.Equal(t, []byte("refunded"), meta.Undo["charge"])
+		assert.Zero(t, balance)
+	})
+}
+
+func TestParticipantMaxTransactions(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	participant := NewParticipant(func(_ context.Context, n *Notification) ([]byte, error) {
+		if n.TransactionID == "slow" {
+			close(started)
+			<-release
+		}
+		return []byte(n.TransactionID), nil
+	}, nil, WithMaxTransactions(2))
+	notify := func(transactionID string) {
+		body, err := json.Marshal(&Notification{TransactionID: transactionID, Action: ActionDo, Meta: &Meta{}})
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		participant.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	slow := make(chan struct{})
+	go func() {
+		defer close(slow)
+		notify("slow")
+	}()
+	<-started
+
+	// Удаляются состояния самых старых завершённых транзакций; обрабатываемая транзакция сохраняется.
+	for _, id := range []string{"t1", "t2", "t3"} {
+		notify(id)
+	}
+	close(release)
+	<-slow
+	done, _ := participant.Completed("slow")
+	assert.True(t, done)
+	for id, expected := range map[string]bool{"t1": false, "t2": false, "t3": true} {
+		done, _ := participant.Completed(id)
+		assert.Equal(t, expected, done, id)
+	}
+}
@@ -1,11 +1,124 @@
 package dostack
 
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
 type Doer interface {
 	Do() error
 	Undo() error
 }
 
+// ContextDoer есть исполнитель, поддерживающий отмену через контекст.
+// Хранилище вызывает методы с контекстом вместо Do и Undo, если исполнитель их реализует.
+// Исполнитель без поддержки контекста не прерывается: контекст проверяется только перед началом исполнения.
+type ContextDoer interface {
+	Doer
+	DoContext(ctx context.Context) error
+	UndoContext(ctx context.Context) error
+}
+
+// Evicter есть исполнитель, освобождающий ресурсы вызова при вытеснении действия из хранилища:
+// когда действие выходит за пределы глубины стека или теряет возможность повтора.
+// Вытесненное действие больше не будет ни откатываться, ни повторяться.
+// Evict вызывается только для исполнителей, созданных фабрикой параметризованной команды:
+// исполнитель, зарегистрированный напрямую, общий для всех вызовов команды, и его ресурсы нужны оставшимся в стеке вызовам.
+type Evicter interface {
+	Evict()
+}
+
+// CommandFactory создаёт исполнителя для конкретного вызова параметризованной команды.
+// Исполнитель захватывает аргументы и всё необходимое для отката именно этого вызова.
+type CommandFactory[T any] func(args T) (Doer, error)
+
 type doer struct{ do, undo func() error }
 
 func (slf *doer) Do() error   { return slf.do() }
 func (slf *doer) Undo() error { return slf.undo() }
+
+type contextDoer struct {
+	do, undo func(ctx context.Context) error
+}
+
+func (slf *contextDoer) Do() error                             { return slf.do(context.Background()) }
+func (slf *contextDoer) Undo() error                           { return slf.undo(context.Background()) }
+func (slf *contextDoer) DoContext(ctx context.Context) error   { return slf.do(ctx) }
+func (slf *contextDoer) UndoContext(ctx context.Context) error { return slf.undo(ctx) }
+
+// Call есть вызов команды в составе группы.
+type Call struct {
+	Name string // Имя зарегистрированной команды.
+	Args any    // Аргументы параметризованной команды.
+}
+
+// group есть составной исполнитель: исполняет шаги по порядку, а откатывает в обратном порядке.
+type group struct{ steps []*entry }
+
+func (slf *group) Do() error   { return slf.DoContext(context.Background()) }
+func (slf *group) Undo() error { return slf.UndoContext(context.Background()) }
+
+// DoContext исполняет шаги группы. При неудаче шага уже исполненные шаги откатываются;
+// откат производится и после отмены контекста, иначе группа осталась бы исполненной частично.
+func (slf *group) DoContext(ctx context.Context) error {
+	for i, step := range slf.steps {
+		if err := step.run(ctx, false); err != nil {
+			err = fmt.Errorf("command <%v> execution failure: %w", step.name, err)
+			return errors.Join(err, undo(context.WithoutCancel(ctx), slf.steps[:i]))
+		}
+	}
+	return nil
+}
+
+// UndoContext откатывает шаги группы в обратном порядке.
+func (slf *group) UndoContext(ctx context.Context) error { return undo(ctx, slf.steps) }
+
+// Evict вытесняет шаги группы.
+func (slf *group) Evict() { evict(slf.steps) }
+
+// undoable сообщает, поддерживает ли откат хотя бы один шаг группы.
+func (slf *group) undoable() bool {
+	for _, v := range slf.steps {
+		if v.undoable {
+			return true
+		}
+	}
+	return false
+}
+
+// undo откатывает шаги в обратном порядке. Неудача отката шага не прерывает откат остальных.
+func undo(ctx context.Context, steps []*entry) error {
+	var errs []error
+	for i := len(steps) - 1; i >= 0; i-- {
+		if !steps[i].undoable {
+			continue
+		}
+		if err := steps[i].run(ctx, true); err != nil {
+			errs = append(errs, fmt.Errorf("command <%v> undo failure: %w", steps[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// evict сообщает исполнителям о вытеснении действий.
+// Общие исполнители команд и исполнители, не реализующие Evicter, пропускаются.
+func evict(entries []*entry) {
+	for _, v := range entries {
+		if !v.owned {
+			continue
+		}
+		if evicter, ok := v.doer.(Evicter); ok {
+			if v.mu != nil {
+				v.mu.Lock()
+			}
+			_ = safeExec(func() error {
+				evicter.Evict()
+				return nil
+			})
+			if v.mu != nil {
+				v.mu.Unlock()
+			}
+		}
+	}
+}
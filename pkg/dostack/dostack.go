@@ -1,83 +1,340 @@
 package dostack
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
 
-// loggedAction есть элемент журнала совершённых действий и откатов.
-type loggedAction struct {
-	name      string    // Имя действия.
-	timestamp time.Time // Время совершения.
-	undo      bool      // Флаг того, что действиие было откатом.
-}
+// ErrBusy возвращается при попытке отката или повтора, когда последнее действие соответствующего стека
+// ещё откатывается или повторяется другим вызовом. Откат следующего за ним действия нарушил бы порядок отката.
+var ErrBusy = errors.New("last command is busy")
 
 // command представляет единицу исполнения.
+// Вызовы одной команды исполняются поочерёдно, вызовы разных команд — одновременно.
 type command struct {
-	Doer          // Переданный извне исполнитель.
-	undoable bool // Флаг того, поддерживает ли исполнитель откат.
+	Doer                                       // Переданный извне исполнитель.
+	mu       sync.Mutex                        // Блокировка на время исполнения или отката вызова команды.
+	factory  func(args any) (Doer, any, error) // Фабрика исполнителей параметризованной команды; возвращает и приведённые аргументы.
+	undoable bool                              // Флаг того, поддерживает ли исполнитель откат.
+	service  bool                              // Флаг служебной команды, которая не попадает в стек и журнал (например, явный откат).
+}
+
+// entry есть элемент стека: совершённое действие вместе с исполнителем, откатывающим именно этот вызов.
+type entry struct {
+	name     string        // Имя действия.
+	args     any           // Аргументы параметризованного действия.
+	doer     Doer          // Исполнитель вызова.
+	mu       *sync.Mutex   // Блокировка команды; для группы, исполненной через DoGroup, отсутствует.
+	timeout  time.Duration // Ограничение времени исполнения и отката; нулевое значение означает отсутствие ограничения.
+	undoable bool          // Флаг того, поддерживает ли исполнитель откат.
+	grouped  bool          // Флаг группы, исполненной через DoGroup.
+	busy     bool          // Флаг того, что действие в данный момент откатывается или повторяется.
+	owned    bool          // Флаг исполнителя, созданного для этого вызова, а не общего для всех вызовов команды.
+	doneAt   time.Time     // Время исполнения действия.
+	seq      uint64        // Порядковый номер попадания в стек.
 }
 
 // Dostack есть хранилище команд. Выполненные команды кладутся в стек.
 // В порядке извлечения из стека можно производить откат.
+//
+// Команды исполняются вне общей блокировки хранилища, поэтому независимые команды не ждут друг друга.
+// Действие попадает в стек в момент завершения исполнения: порядок стека есть порядок завершения действий.
 type Dostack struct {
 	mu       sync.Mutex
-	commands map[string]*command // Список зарегистрированных команд.
-	stack    []string            // Стек имён совершённых действий.
-	log      []loggedAction      // Журнал совершённых действий и откатов.
+	commands map[string]*command      // Список зарегистрированных команд.
+	timeouts map[string]time.Duration // Ограничения времени исполнения команд.
+	stack    []*entry                 // Стек совершённых действий.
+	redo     []*entry                 // Стек откатанных действий, доступных для повтора.
+	log      []Record                 // Журнал совершённых действий и откатов.
+	journal  JournalWriter            // Внешний журнал; может отсутствовать.
+	maxDepth int                      // Предельная глубина стека; нулевое значение означает отсутствие предела.
+	maxLog   int                      // Предельная длина журнала; нулевое значение означает отсутствие предела.
+	policy   UndoPolicy               // Политика обработки неудачного отката.
+	poisoned *UndoError               // Неудачный откат, заблокировавший хранилище.
+	failed   *entry                   // Действие, откат которого заблокировал хранилище.
+	seq      uint64                   // Порядковый номер последнего попадания действия в стек.
+	// Отметки состояния стека: порядковый номер последнего действия на момент отметки.
+	checkpoints map[string]uint64
 }
 
 // New создаёт новое хранилище.
 func New(options ...Option) *Dostack {
-	dostack := &Dostack{commands: map[string]*command{}}
+	dostack := &Dostack{commands: map[string]*command{}, timeouts: map[string]time.Duration{}, checkpoints: map[string]uint64{}}
 	for _, v := range options {
 		v(dostack)
 	}
 	return dostack
 }
 
-// Do исполняет зарегистрированную команду.
-func (slf *Dostack) Do(name string) error {
-	slf.mu.Lock()
-	defer slf.mu.Unlock()
+// Do исполняет зарегистрированную команду. Новое действие делает недоступным повтор откатанных ранее действий.
+// Параметризованная команда исполняется с нулевым значением аргументов.
+// Отмена контекста прерывает исполнение команды, если её исполнитель реализует ContextDoer.
+func (slf *Dostack) Do(ctx context.Context, name string) error { return slf.DoWith(ctx, name, nil) }
 
-	command, ok := slf.commands[name]
-	if !ok {
-		return fmt.Errorf("command <%v> is not registered", name)
+// DoWith исполняет зарегистрированную команду с аргументами.
+// Для параметризованной команды фабрика создаёт исполнителя, который затем используется для отката именно этого вызова.
+// Команды без параметров не принимают аргументов.
+func (slf *Dostack) DoWith(ctx context.Context, name string, args any) error {
+	slf.mu.Lock()
+	if err := slf.check(); err != nil {
+		slf.mu.Unlock()
+		return err
+	}
+	command, e, err := slf.resolve(name, args)
+	slf.mu.Unlock()
+	if err != nil {
+		return err
 	}
 
-	if err := safeExec(command.Do); err != nil {
+	if err := e.run(ctx, false); err != nil {
 		return fmt.Errorf("command <%v> execution failure: %w", name, err)
 	}
+	if command.service {
+		return nil
+	}
 
-	slf.log = append(slf.log, loggedAction{name: name, timestamp: time.Now().UTC()})
-	slf.stack = append(slf.stack, name)
+	slf.mu.Lock()
+	evicted, err := slf.push(e)
+	slf.mu.Unlock()
+	evict(evicted)
+	return err
+}
 
-	return nil
+// DoGroup исполняет группу команд как единое целое: при неудаче одной из команд
+// уже исполненные команды группы откатываются в обратном порядке.
+// Успешно исполненная группа занимает в стеке один элемент с заданным именем и откатывается целиком.
+func (slf *Dostack) DoGroup(ctx context.Context, name string, calls ...Call) error {
+	slf.mu.Lock()
+	if err := slf.check(); err != nil {
+		slf.mu.Unlock()
+		return err
+	}
+	g, err := slf.group(calls)
+	slf.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("group <%v> creation failure: %w", name, err)
+	}
+
+	// В журнал попадают приведённые аргументы шагов.
+	calls = make([]Call, 0, len(g.steps))
+	for _, v := range g.steps {
+		calls = append(calls, Call{v.name, v.args})
+	}
+	e := &entry{name: name, args: calls, doer: g, undoable: g.undoable(), grouped: true, owned: true}
+	if err := e.run(ctx, false); err != nil {
+		return fmt.Errorf("group <%v> execution failure: %w", name, err)
+	}
+
+	slf.mu.Lock()
+	evicted, err := slf.push(e)
+	slf.mu.Unlock()
+	evict(evicted)
+	return err
 }
 
 // Undo вызывает откат последнего совершённого действия, если он был поддержан.
-func (slf *Dostack) Undo() error {
+// Откатанное действие становится доступным для повтора; действие без поддержки отката просто удаляется из стека.
+// Если последнее действие уже откатывается другим вызовом, возвращается ErrBusy.
+// Неудачный откат обрабатывается согласно политике хранилища и возвращается в виде *UndoError.
+func (slf *Dostack) Undo(ctx context.Context) error {
+	_, err := slf.undo(ctx, 0)
+	return err
+}
+
+// UndoTo откатывает все действия, попавшие в стек после отметки с заданным именем.
+// При неудаче очередного отката работа прекращается и возвращается *CheckpointError с числом откатанных действий.
+// Действия, совершённые до отметки, не затрагиваются, даже если часть из них уже откатана.
+func (slf *Dostack) UndoTo(ctx context.Context, checkpoint string) error {
+	slf.mu.Lock()
+	seq, ok := slf.checkpoints[checkpoint]
+	slf.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("checkpoint <%v> is not found", checkpoint)
+	}
+
+	for undone := 0; ; undone++ {
+		ok, err := slf.undo(ctx, seq)
+		if err != nil {
+			return &CheckpointError{Name: checkpoint, Undone: undone, Err: err}
+		}
+		if !ok {
+			return nil
+		}
+	}
+}
+
+// Checkpoint отмечает текущее состояние стека именем, к которому затем можно вернуться через UndoTo.
+// Повторная отметка с тем же именем заменяет предыдущую.
+func (slf *Dostack) Checkpoint(name string) error {
 	slf.mu.Lock()
 	defer slf.mu.Unlock()
+	slf.checkpoints[name] = slf.seq
+	return slf.record(KindCheckpoint, &entry{name: name})
+}
 
-	if len(slf.stack) == 0 {
-		return nil
+// undo откатывает последнее действие, если его порядковый номер больше after.
+// Сообщает, было ли действие извлечено из стека.
+func (slf *Dostack) undo(ctx context.Context, after uint64) (bool, error) {
+	slf.mu.Lock()
+	if err := slf.check(); err != nil {
+		slf.mu.Unlock()
+		return false, err
+	}
+	last := top(slf.stack)
+	if last == nil || last.seq <= after {
+		slf.mu.Unlock()
+		return false, nil
+	}
+	if last.busy {
+		slf.mu.Unlock()
+		return false, fmt.Errorf("command <%v> undo failure: %w", last.name, ErrBusy)
+	}
+	last.busy = true
+	policy := slf.policy
+	slf.mu.Unlock()
+
+	var undoErr *UndoError
+	if last.undoable {
+		undoErr = policy.undo(ctx, last)
 	}
 
-	lastActionName := slf.stack[len(slf.stack)-1]
-	command := slf.commands[lastActionName]
-	if command.undoable {
-		if err := safeExec(command.Undo); err != nil {
-			return err
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	last.busy = false
+	if undoErr != nil {
+		switch policy.OnFailure {
+		case FailureSkip:
+			slf.stack = remove(slf.stack, last)
+			return true, errors.Join(undoErr, slf.record(KindSkip, last))
+		case FailurePoison:
+			if slf.poisoned == nil {
+				slf.poisoned, slf.failed = undoErr, last
+			}
 		}
+		return false, undoErr
 	}
 
-	slf.log = append(slf.log, loggedAction{name: lastActionName, timestamp: time.Now().UTC(), undo: true})
-	slf.stack = slf.stack[:len(slf.stack)-1]
+	slf.stack = remove(slf.stack, last)
+	if last.undoable {
+		slf.redo = append(slf.redo, last)
+	}
 
-	return nil
+	return true, slf.record(KindUndo, last)
+}
+
+// Unpoison снимает блокировку хранилища после неудачного отката.
+// Флаг skip есть признак того, что неоткатанное действие удаляется из стека; иначе его откат можно запросить повторно.
+func (slf *Dostack) Unpoison(skip bool) error {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+
+	if slf.poisoned == nil {
+		return nil
+	}
+	failed := slf.failed
+	slf.poisoned, slf.failed = nil, nil
+	if !skip {
+		return nil
+	}
+	return slf.skip(failed)
+}
+
+// Poisoned возвращает неудачный откат, заблокировавший хранилище, или nil.
+func (slf *Dostack) Poisoned() error {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if slf.poisoned == nil {
+		return nil
+	}
+	return slf.poisoned
+}
+
+// Redo повторно исполняет последнее откатанное действие с прежними аргументами и возвращает его в стек.
+func (slf *Dostack) Redo(ctx context.Context) error {
+	slf.mu.Lock()
+	if err := slf.check(); err != nil {
+		slf.mu.Unlock()
+		return err
+	}
+	last := top(slf.redo)
+	if last == nil {
+		slf.mu.Unlock()
+		return nil
+	}
+	if last.busy {
+		slf.mu.Unlock()
+		return fmt.Errorf("command <%v> execution failure: %w", last.name, ErrBusy)
+	}
+	last.busy = true
+	slf.mu.Unlock()
+
+	err := last.run(ctx, false)
+
+	slf.mu.Lock()
+	last.busy = false
+	if err != nil {
+		slf.mu.Unlock()
+		return fmt.Errorf("command <%v> execution failure: %w", last.name, err)
+	}
+
+	slf.redo = remove(slf.redo, last)
+	last.doneAt = time.Now().UTC()
+	slf.seq++
+	last.seq = slf.seq
+	slf.stack = append(slf.stack, last)
+	evicted := slf.trim()
+	err = slf.record(KindRedo, last)
+	slf.mu.Unlock()
+
+	evict(evicted)
+	return err
+}
+
+// CanUndo сообщает, есть ли в стеке действия для отката.
+func (slf *Dostack) CanUndo() bool {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	return len(slf.stack) != 0
+}
+
+// CanRedo сообщает, есть ли откатанные действия для повтора.
+func (slf *Dostack) CanRedo() bool {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	return len(slf.redo) != 0
+}
+
+// History возвращает копию журнала совершённых действий и откатов.
+func (slf *Dostack) History() []Record {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	return slices.Clone(slf.log)
+}
+
+// CommandInfo есть описание зарегистрированной команды.
+type CommandInfo struct {
+	Name          string // Имя команды.
+	Parameterized bool   // Флаг команды, принимающей аргументы.
+	Undoable      bool   // Флаг того, поддерживает ли команда откат.
+	Service       bool   // Флаг служебной команды.
+}
+
+// Commands возвращает описания зарегистрированных команд, упорядоченные по имени.
+func (slf *Dostack) Commands() []CommandInfo {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+
+	commands := make([]CommandInfo, 0, len(slf.commands))
+	for name, v := range slf.commands {
+		commands = append(commands, CommandInfo{name, v.factory != nil, v.undoable, v.service})
+	}
+	slices.SortFunc(commands, func(a, b CommandInfo) int { return strings.Compare(a.Name, b.Name) })
+	return commands
 }
 
 // AddDoer добавляет к списку действий сущность, реализующую интерфейс команды Doer.
@@ -96,6 +353,158 @@ func (slf *Dostack) AddFuncs(name string, do, undo func() error) {
 	WithFuncs(name, do, undo)(slf)
 }
 
+// resolve находит зарегистрированную команду и формирует элемент стека для её вызова с аргументами.
+func (slf *Dostack) resolve(name string, args any) (*command, *entry, error) {
+	command, ok := slf.commands[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("command <%v> is not registered", name)
+	}
+
+	e := &entry{name: name, args: args, mu: &command.mu, timeout: slf.timeouts[name], undoable: command.undoable}
+	if command.factory == nil {
+		if args != nil {
+			return nil, nil, fmt.Errorf("command <%v> does not accept arguments", name)
+		}
+		e.doer = command.Doer
+		return command, e, nil
+	}
+	doer, args, err := command.factory(args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("command <%v> creation failure: %w", name, err)
+	}
+	e.doer, e.args, e.owned = doer, args, true
+	return command, e, nil
+}
+
+// group формирует составного исполнителя из вызовов команд. Служебные команды в группу не допускаются.
+func (slf *Dostack) group(calls []Call) (*group, error) {
+	g := &group{}
+	for _, v := range calls {
+		command, e, err := slf.resolve(v.Name, v.Args)
+		if err != nil {
+			return nil, err
+		}
+		if command.service {
+			return nil, fmt.Errorf("service command <%v> can not be grouped", v.Name)
+		}
+		g.steps = append(g.steps, e)
+	}
+	return g, nil
+}
+
+// push кладёт совершённое действие в стек и журнал. Новое действие делает недоступным повтор откатанных ранее.
+// Возвращает вытесненные действия: откатанные ранее и вышедшие за пределы глубины стека.
+func (slf *Dostack) push(e *entry) ([]*entry, error) {
+	e.doneAt = time.Now().UTC()
+	slf.seq++
+	e.seq = slf.seq
+	slf.stack = append(slf.stack, e)
+	evicted := slf.trim()
+	for _, v := range slf.redo {
+		// Повторяемое в данный момент действие вернётся в стек.
+		if !v.busy {
+			evicted = append(evicted, v)
+		}
+	}
+	slf.redo = nil
+	if e.grouped {
+		return evicted, slf.record(KindGroup, e)
+	}
+	return evicted, slf.record(KindDo, e)
+}
+
+// skip удаляет действие из стека без отката и записывает пропуск в журнал.
+// Действие, которого уже нет в стеке, пропускается.
+func (slf *Dostack) skip(e *entry) error {
+	if !slices.Contains(slf.stack, e) {
+		return nil
+	}
+	slf.stack = remove(slf.stack, e)
+	return slf.record(KindSkip, e)
+}
+
+// check возвращает ошибку, если хранилище заблокировано после неудачного отката.
+func (slf *Dostack) check() error {
+	if slf.poisoned != nil {
+		return fmt.Errorf("%w: %w", ErrPoisoned, slf.poisoned)
+	}
+	return nil
+}
+
+// trim удаляет из основания стека действия, вышедшие за пределы его глубины, и возвращает их.
+// Действия, которые в данный момент откатываются, не удаляются.
+func (slf *Dostack) trim() []*entry {
+	var evicted []*entry
+	for i := 0; slf.maxDepth > 0 && len(slf.stack) > slf.maxDepth && i < len(slf.stack); {
+		if slf.stack[i].busy {
+			i++
+			continue
+		}
+		evicted = append(evicted, slf.stack[i])
+		slf.stack = slices.Delete(slf.stack, i, i+1)
+	}
+	return evicted
+}
+
+// record добавляет запись о действии в журнал и передаёт её во внешний журнал при его наличии.
+// Ошибка внешнего журнала не отменяет совершённого действия.
+func (slf *Dostack) record(kind string, e *entry) error {
+	record := Record{Kind: kind, Name: e.name, Args: e.args, Timestamp: time.Now().UTC()}
+	slf.log = append(slf.log, record)
+	if slf.maxLog > 0 && len(slf.log) > slf.maxLog {
+		slf.log = slices.Delete(slf.log, 0, len(slf.log)-slf.maxLog)
+	}
+	if slf.journal != nil {
+		if err := slf.journal.Write(record); err != nil {
+			return fmt.Errorf("command <%v> journal failure: %w", e.name, err)
+		}
+	}
+	return nil
+}
+
+// run исполняет действие или откат под блокировкой команды и с учётом ограничения времени её исполнения.
+func (slf *entry) run(ctx context.Context, undo bool) error {
+	if slf.mu != nil {
+		slf.mu.Lock()
+		defer slf.mu.Unlock()
+	}
+	if slf.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, slf.timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if doer, ok := slf.doer.(ContextDoer); ok {
+		if undo {
+			return safeExec(func() error { return doer.UndoContext(ctx) })
+		}
+		return safeExec(func() error { return doer.DoContext(ctx) })
+	}
+	if undo {
+		return safeExec(slf.doer.Undo)
+	}
+	return safeExec(slf.doer.Do)
+}
+
+// top возвращает последний элемент стека или nil для пустого стека.
+func top(stack []*entry) *entry {
+	if len(stack) == 0 {
+		return nil
+	}
+	return stack[len(stack)-1]
+}
+
+// remove удаляет элемент из стека, сохраняя порядок остальных.
+func remove(stack []*entry, e *entry) []*entry {
+	if i := slices.Index(stack, e); i >= 0 {
+		return slices.Delete(stack, i, i+1)
+	}
+	return stack
+}
+
 // safeExec восстанавливает исполнение из состояния паники при необходимости.
 func safeExec(f func() error) (err error) {
 	defer func() {
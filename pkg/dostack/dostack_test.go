@@ -1,8 +1,12 @@
 package dostack
 
 import (
+	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -20,24 +24,26 @@ func (slf *testDoer) Undo() error {
 }
 
 func TestDostack(t *testing.T) {
-	var i int
+	ctx := context.Background()
+	// Команды "a" и "b" исполняются одновременно, поэтому общий счётчик атомарный.
+	var i atomic.Int64
 	d := &testDoer{}
 	testDostack := New(
 		WithFuncs(
 			"a",
 			func() error {
-				i++
+				i.Add(1)
 				return nil
 			},
 			func() error {
-				i--
+				i.Add(-1)
 				return nil
 			},
 		),
 		WithFunc(
 			"b",
 			func() error {
-				i++
+				i.Add(1)
 				return nil
 			},
 		),
@@ -49,15 +55,15 @@ func TestDostack(t *testing.T) {
 		wg.Add(3)
 		go func() {
 			defer wg.Done()
-			testDostack.Do("a")
+			testDostack.Do(ctx, "a")
 		}()
 		go func() {
 			defer wg.Done()
-			testDostack.Do("b")
+			testDostack.Do(ctx, "b")
 		}()
 		go func() {
 			defer wg.Done()
-			testDostack.Do("c")
+			testDostack.Do(ctx, "c")
 		}()
 	}
 	wg.Wait()
@@ -65,11 +71,449 @@ func TestDostack(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			testDostack.Undo()
+			testDostack.Undo(ctx)
 		}()
 	}
 	wg.Wait()
 
-	assert.Equal(t, 1000, i)
+	assert.Equal(t, int64(1000), i.Load())
 	assert.Equal(t, 0, d.i)
 }
+
+func TestDostackRedo(t *testing.T) {
+	ctx := context.Background()
+	var i, j int
+	testDostack := New(
+		WithFuncs("inc", func() error { i++; return nil }, func() error { i--; return nil }),
+		WithFunc("print", func() error { j++; return nil }),
+		WithExplicitUndo("undo"),
+		WithExplicitRedo("redo"),
+	)
+	assert.False(t, testDostack.CanUndo())
+	assert.False(t, testDostack.CanRedo())
+
+	assert.NoError(t, testDostack.Do(ctx, "inc"))
+	assert.NoError(t, testDostack.Do(ctx, "inc"))
+	assert.NoError(t, testDostack.Do(ctx, "print"))
+	assert.True(t, testDostack.CanUndo())
+
+	// Действие без поддержки отката не становится доступным для повтора.
+	assert.NoError(t, testDostack.Do(ctx, "undo"))
+	assert.False(t, testDostack.CanRedo())
+	assert.NoError(t, testDostack.Do(ctx, "undo"))
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Zero(t, i)
+	assert.False(t, testDostack.CanUndo())
+	assert.True(t, testDostack.CanRedo())
+
+	assert.NoError(t, testDostack.Do(ctx, "redo"))
+	assert.Equal(t, 1, i)
+	assert.NoError(t, testDostack.Redo(ctx))
+	assert.Equal(t, 2, i)
+	assert.False(t, testDostack.CanRedo())
+	assert.NoError(t, testDostack.Redo(ctx))
+	assert.Equal(t, 2, i)
+
+	// Новое действие очищает стек повтора.
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.True(t, testDostack.CanRedo())
+	assert.NoError(t, testDostack.Do(ctx, "print"))
+	assert.False(t, testDostack.CanRedo())
+	assert.Equal(t, 1, i)
+	assert.Equal(t, 2, j)
+}
+
+type testSetter struct {
+	storage map[string]string
+	key     string
+	value   string
+	prev    *string
+}
+
+func (slf *testSetter) Do() error {
+	if prev, ok := slf.storage[slf.key]; ok {
+		slf.prev = &prev
+	}
+	slf.storage[slf.key] = slf.value
+	return nil
+}
+
+func (slf *testSetter) Undo() error {
+	if slf.prev == nil {
+		delete(slf.storage, slf.key)
+		return nil
+	}
+	slf.storage[slf.key] = *slf.prev
+	return nil
+}
+
+func TestDostackFactory(t *testing.T) {
+	ctx := context.Background()
+	storage := map[string]string{}
+	testDostack := New(
+		WithFactory(
+			"update",
+			func(args [2]string) (Doer, error) {
+				if args[0] == "" {
+					return nil, errors.New("empty key")
+				}
+				return &testSetter{storage: storage, key: args[0], value: args[1]}, nil
+			},
+			true,
+		),
+		WithFunc("noop", func() error { return nil }),
+	)
+
+	assert.NoError(t, testDostack.DoWith(ctx, "update", [2]string{"a", "1"}))
+	assert.NoError(t, testDostack.DoWith(ctx, "update", [2]string{"b", "2"}))
+	assert.NoError(t, testDostack.DoWith(ctx, "update", [2]string{"a", "3"}))
+	assert.Equal(t, map[string]string{"a": "3", "b": "2"}, storage)
+
+	// Ошибки фабрики, неверный тип аргументов и аргументы для команды без параметров не попадают в стек.
+	assert.Error(t, testDostack.Do(ctx, "update"))
+	assert.Error(t, testDostack.DoWith(ctx, "update", "a=1"))
+	assert.Error(t, testDostack.DoWith(ctx, "noop", 1))
+
+	// Каждый откат возвращает состояние именно своего вызова.
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, storage)
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Equal(t, map[string]string{"a": "1"}, storage)
+	assert.NoError(t, testDostack.Redo(ctx))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, storage)
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Empty(t, storage)
+	assert.False(t, testDostack.CanUndo())
+}
+
+func TestDostackGroup(t *testing.T) {
+	ctx := context.Background()
+	storage := map[string]string{}
+	testDostack := New(
+		WithFactory(
+			"update",
+			func(args [2]string) (Doer, error) {
+				return &testSetter{storage: storage, key: args[0], value: args[1]}, nil
+			},
+			true,
+		),
+		WithFunc("fail", func() error { panic("failure") }),
+		WithGroup("init", Call{"update", [2]string{"a", "1"}}, Call{"update", [2]string{"b", "2"}}),
+		WithExplicitUndo("undo"),
+	)
+
+	// Неудача в середине группы откатывает уже исполненные команды.
+	assert.Error(t, testDostack.DoGroup(
+		ctx,
+		"batch",
+		Call{"update", [2]string{"a", "1"}},
+		Call{"update", [2]string{"b", "2"}},
+		Call{"fail", nil},
+		Call{"update", [2]string{"c", "3"}},
+	))
+	assert.Empty(t, storage)
+	assert.False(t, testDostack.CanUndo())
+
+	// Служебные и незарегистрированные команды в группу не допускаются.
+	assert.Error(t, testDostack.DoGroup(ctx, "batch", Call{"undo", nil}))
+	assert.Error(t, testDostack.DoGroup(ctx, "batch", Call{"unknown", nil}))
+
+	// Группа занимает в стеке один элемент.
+	assert.NoError(t, testDostack.DoGroup(ctx, "batch", Call{"update", [2]string{"a", "1"}}, Call{"update", [2]string{"a", "2"}}))
+	assert.NoError(t, testDostack.Do(ctx, "init"))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, storage)
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Equal(t, map[string]string{"a": "2"}, storage)
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Empty(t, storage)
+	assert.False(t, testDostack.CanUndo())
+	assert.NoError(t, testDostack.Redo(ctx))
+	assert.Equal(t, map[string]string{"a": "2"}, storage)
+}
+
+func TestDostackContext(t *testing.T) {
+	ctx := context.Background()
+	var i atomic.Int64
+	release := make(chan struct{})
+	testDostack := New(
+		WithFuncs(
+			"slow",
+			func() error {
+				<-release
+				i.Add(1)
+				return nil
+			},
+			func() error {
+				i.Add(-1)
+				return nil
+			},
+		),
+		WithFuncs(
+			"fast",
+			func() error {
+				i.Add(10)
+				return nil
+			},
+			func() error {
+				i.Add(-10)
+				return nil
+			},
+		),
+		WithContextFuncs(
+			"wait",
+			func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			nil,
+		),
+		WithTimeout("wait", 10*time.Millisecond),
+		WithExplicitUndo("undo"),
+	)
+
+	// Долгая команда не блокирует исполнение и откат других команд.
+	done := make(chan error)
+	go func() { done <- testDostack.Do(ctx, "slow") }()
+	assert.NoError(t, testDostack.Do(ctx, "fast"))
+	assert.NoError(t, testDostack.Do(ctx, "undo"))
+	assert.Equal(t, int64(0), i.Load())
+	assert.NoError(t, testDostack.Do(ctx, "fast"))
+	close(release)
+	assert.NoError(t, <-done)
+
+	// Порядок стека есть порядок завершения действий: долгая команда откатывается первой.
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Equal(t, int64(10), i.Load())
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Equal(t, int64(0), i.Load())
+
+	// Ограничение времени прерывает команду, поддерживающую контекст.
+	err := testDostack.Do(ctx, "wait")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, testDostack.CanUndo())
+
+	// Команда не исполняется с отменённым контекстом.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, testDostack.Do(cancelled, "fast"), context.Canceled)
+	assert.Equal(t, int64(0), i.Load())
+
+	// Пока последнее действие откатывается, откат предыдущего не начинается.
+	started, release := make(chan struct{}), make(chan struct{})
+	testDostack.AddFuncs("slow undo", func() error { return nil }, func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	assert.NoError(t, testDostack.Do(ctx, "fast"))
+	assert.NoError(t, testDostack.Do(ctx, "slow undo"))
+	go func() { done <- testDostack.Undo(ctx) }()
+	<-started
+	assert.ErrorIs(t, testDostack.Undo(ctx), ErrBusy)
+	assert.Equal(t, int64(10), i.Load())
+	close(release)
+	assert.NoError(t, <-done)
+	assert.NoError(t, testDostack.Undo(ctx))
+	assert.Equal(t, int64(0), i.Load())
+}
+
+type testEvicter struct {
+	testDoer
+	evicted *atomic.Int64
+}
+
+func (slf *testEvicter) Evict() { slf.evicted.Add(1) }
+
+func TestDostackLimits(t *testing.T) {
+	ctx := context.Background()
+	var evicted atomic.Int64
+	testDostack := New(
+		WithFactory(
+			"inc",
+			func(int) (Doer, error) { return &testEvicter{evicted: &evicted}, nil },
+			true,
+		),
+		WithMaxDepth(3),
+		WithMaxHistory(4),
+	)
+
+	// Действия за пределами глубины стека вытесняются из его основания.
+	for range 5 {
+		assert.NoError(t, testDostack.Do(ctx, "inc"))
+	}
+	assert.Equal(t, int64(2), evicted.Load())
+	assert.Len(t, testDostack.History(), 4)
+	for range 3 {
+		assert.NoError(t, testDostack.Undo(ctx))
+	}
+	assert.False(t, testDostack.CanUndo())
+
+	// Повтор возвращает действие в стек, а новое действие вытесняет оставшиеся откатанные.
+	assert.NoError(t, testDostack.Redo(ctx))
+	assert.NoError(t, testDostack.Do(ctx, "inc"))
+	assert.Equal(t, int64(4), evicted.Load())
+	assert.False(t, testDostack.CanRedo())
+
+	history := testDostack.History()
+	assert.Len(t, history, 4)
+	assert.Equal(t, KindDo, history[3].Kind)
+	assert.Equal(t, KindRedo, history[2].Kind)
+
+	// Общий исполнитель команды не вытесняется: его ресурсы нужны оставшимся в стеке вызовам.
+	var shared atomic.Int64
+	testDostack = New(WithDoer("shared", &testEvicter{evicted: &shared}, true), WithMaxDepth(1))
+	for range 3 {
+		assert.NoError(t, testDostack.Do(ctx, "shared"))
+	}
+	assert.Zero(t, shared.Load())
+}
+
+func TestDostackUndoPolicy(t *testing.T) {
+	ctx := context.Background()
+	// newDostack создаёт хранилище с командой, откат которой удаётся только с заданной попытки.
+	newDostack := func(policy UndoPolicy, succeedAt int) (*Dostack, *int) {
+		var attempts int
+		return New(
+			WithFuncs(
+				"a",
+				func() error { return nil },
+				func() error {
+					attempts++
+					if attempts < succeedAt {
+						return errors.New("failure")
+					}
+					return nil
+				},
+			),
+			WithUndoPolicy(policy),
+		), &attempts
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		testDostack, attempts := newDostack(UndoPolicy{Attempts: 3, Backoff: time.Millisecond}, 3)
+		assert.NoError(t, testDostack.Do(ctx, "a"))
+		assert.NoError(t, testDostack.Undo(ctx))
+		assert.Equal(t, 3, *attempts)
+		assert.False(t, testDostack.CanUndo())
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		testDostack, _ := newDostack(UndoPolicy{Attempts: 2}, 4)
+		assert.NoError(t, testDostack.Do(ctx, "a"))
+		err := testDostack.Undo(ctx)
+		undoErr := &UndoError{}
+		if assert.ErrorAs(t, err, &undoErr) {
+			assert.Equal(t, "a", undoErr.Name)
+			assert.Equal(t, 2, undoErr.Attempts)
+			assert.False(t, undoErr.DoneAt.IsZero())
+		}
+		assert.True(t, testDostack.CanUndo())
+		assert.NoError(t, testDostack.Undo(ctx))
+		assert.False(t, testDostack.CanUndo())
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		testDostack, _ := newDostack(UndoPolicy{OnFailure: FailureSkip}, 2)
+		assert.NoError(t, testDostack.Do(ctx, "a"))
+		assert.Error(t, testDostack.Undo(ctx))
+		assert.False(t, testDostack.CanUndo())
+		assert.False(t, testDostack.CanRedo())
+		history := testDostack.History()
+		assert.Equal(t, KindSkip, history[len(history)-1].Kind)
+	})
+
+	t.Run("poison", func(t *testing.T) {
+		testDostack, _ := newDostack(UndoPolicy{OnFailure: FailurePoison}, 2)
+		var slow atomic.Int64
+		started, release, done := make(chan struct{}), make(chan struct{}), make(chan error)
+		testDostack.AddFuncs(
+			"slow",
+			func() error {
+				close(started)
+				<-release
+				slow.Add(1)
+				return nil
+			},
+			func() error {
+				slow.Add(-1)
+				return nil
+			},
+		)
+		assert.NoError(t, testDostack.Do(ctx, "a"))
+		assert.NoError(t, testDostack.Do(ctx, "a"))
+		go func() { done <- testDostack.Do(ctx, "slow") }()
+		<-started
+		assert.Error(t, testDostack.Undo(ctx))
+		assert.Error(t, testDostack.Poisoned())
+
+		// Действие, начатое до блокировки, завершается после неудачного отката и попадает в стек над ним.
+		close(release)
+		assert.NoError(t, <-done)
+
+		// Заблокированное хранилище отвергает любые операции до вмешательства оператора.
+		assert.ErrorIs(t, testDostack.Do(ctx, "a"), ErrPoisoned)
+		assert.ErrorIs(t, testDostack.Undo(ctx), ErrPoisoned)
+		assert.ErrorIs(t, testDostack.Redo(ctx), ErrPoisoned)
+
+		// Оператор удаляет именно неоткатанное действие, после чего работа продолжается.
+		assert.NoError(t, testDostack.Unpoison(true))
+		assert.NoError(t, testDostack.Poisoned())
+		assert.NoError(t, testDostack.Undo(ctx))
+		assert.Zero(t, slow.Load())
+		assert.NoError(t, testDostack.Undo(ctx))
+		assert.False(t, testDostack.CanUndo())
+	})
+}
+
+func TestDostackCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	var i int
+	fail := false
+	testDostack := New(
+		WithFuncs(
+			"inc",
+			func() error {
+				i++
+				return nil
+			},
+			func() error {
+				if fail && i == 3 {
+					return errors.New("failure")
+				}
+				i--
+				return nil
+			},
+		),
+	)
+
+	assert.Error(t, testDostack.UndoTo(ctx, "unknown"))
+
+	assert.NoError(t, testDostack.Do(ctx, "inc"))
+	assert.NoError(t, testDostack.Checkpoint("before"))
+	for range 4 {
+		assert.NoError(t, testDostack.Do(ctx, "inc"))
+	}
+
+	// Неудачный откат прерывает возврат к отметке.
+	fail = true
+	err := testDostack.UndoTo(ctx, "before")
+	checkpointErr := &CheckpointError{}
+	if assert.ErrorAs(t, err, &checkpointErr) {
+		assert.Equal(t, "before", checkpointErr.Name)
+		assert.Equal(t, 2, checkpointErr.Undone)
+	}
+	assert.Equal(t, 3, i)
+
+	// После устранения причины возврат к отметке завершается; действия до отметки не затрагиваются.
+	fail = false
+	assert.NoError(t, testDostack.UndoTo(ctx, "before"))
+	assert.Equal(t, 1, i)
+	assert.True(t, testDostack.CanUndo())
+
+	// Повторённые действия попадают в стек после отметки.
+	assert.NoError(t, testDostack.Redo(ctx))
+	assert.NoError(t, testDostack.UndoTo(ctx, "before"))
+	assert.Equal(t, 1, i)
+	assert.Equal(t, KindCheckpoint, testDostack.History()[1].Kind)
+}
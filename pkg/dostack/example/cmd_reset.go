@@ -17,3 +17,6 @@ func (slf *cmdResetMemstats) Undo() error {
 	slf.c.memStats = slf.prev
 	return nil
 }
+
+// Evict освобождает сохранённый снимок, когда сброс больше не может быть откатан.
+func (slf *cmdResetMemstats) Evict() { slf.prev = nil }
@@ -1,15 +1,14 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"licklib/pkg/dostack"
+	"licklib/pkg/dostack/shell"
 	"log"
 	"os"
 	"runtime"
-	"strings"
 )
 
 type collector struct {
@@ -35,14 +34,19 @@ func main() {
 	c := &collector{&runtime.MemStats{}}
 
 	ds := dostack.New(
-		dostack.WithDoer(
+		dostack.WithFactory(
 			"save",
-			&cmdSaveMemstats{c, "metrics.json"},
+			func(path string) (dostack.Doer, error) {
+				if path == "" {
+					path = "metrics.json"
+				}
+				return &cmdSaveMemstats{c, path}, nil
+			},
 			false,
 		),
-		dostack.WithDoer(
+		dostack.WithFactory(
 			"reset",
-			&cmdResetMemstats{c: c},
+			func(string) (dostack.Doer, error) { return &cmdResetMemstats{c: c}, nil },
 			true,
 		),
 		dostack.WithDoer(
@@ -55,22 +59,20 @@ func main() {
 			&cmdUpdateMemstats{c: c},
 			false,
 		),
-		dostack.WithExplicitUndo("undo"),
+		dostack.WithMaxDepth(100),
+		dostack.WithMaxHistory(1_000),
 	)
 
-	scn := bufio.NewScanner(os.Stdin)
-	for {
-		if !scn.Scan() {
-			log.Fatal(scn.Err())
-		}
-
-		t := scn.Text()
-		if strings.HasPrefix(t, "exit") {
-			return
-		}
-		if err := ds.Do(t); err != nil {
-			log.Printf("command invoke error: %v\n", err)
+	// Сценарий из аргумента командной строки исполняется до начала интерактивной работы.
+	// В интерактивном режиме варианты команд выводятся по вводу начала имени, табуляции и Enter, например "re<Tab><Enter>".
+	sh := shell.New(ds)
+	ctx := context.Background()
+	if len(os.Args) > 1 {
+		if err := sh.RunFile(ctx, os.Args[1]); err != nil {
+			log.Fatal(err)
 		}
-		fmt.Println()
+	}
+	if err := sh.Run(ctx, os.Stdin); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,234 @@
+package dostack
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	KindDo         = "do"         // Исполнение команды.
+	KindGroup      = "group"      // Исполнение группы команд через DoGroup.
+	KindUndo       = "undo"       // Откат последнего действия.
+	KindRedo       = "redo"       // Повтор последнего откатанного действия.
+	KindSkip       = "skip"       // Удаление из стека действия, откат которого не удался.
+	KindCheckpoint = "checkpoint" // Отметка состояния стека.
+)
+
+// Record есть запись журнала совершённых действий и откатов.
+// Аргументы записей, прочитанных из внешнего журнала, представлены в виде json.RawMessage.
+type Record struct {
+	Kind      string    // Вид записи.
+	Name      string    // Имя действия.
+	Args      any       // Аргументы параметризованного действия или вызовы группы.
+	Timestamp time.Time // Время совершения.
+}
+
+// JournalWriter есть внешний журнал, в который хранилище дописывает записи о действиях.
+type JournalWriter interface {
+	Write(record Record) error
+}
+
+// JournalReader читает записи внешнего журнала в порядке их добавления. По окончании журнала возвращает io.EOF.
+type JournalReader interface {
+	Read() (Record, error)
+}
+
+// OpenJournal открывает файл журнала только на дозапись, создавая его при необходимости.
+func OpenJournal(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// Replay восстанавливает состояние хранилища, повторно исполняя действия из внешнего журнала.
+// На время восстановления запись во внешний журнал отключается, поэтому его можно читать из того же файла.
+// Восстановление должно производиться до начала работы с хранилищем.
+func (slf *Dostack) Replay(ctx context.Context, r JournalReader) error {
+	slf.mu.Lock()
+	journal := slf.journal
+	slf.journal = nil
+	slf.mu.Unlock()
+	defer func() {
+		slf.mu.Lock()
+		slf.journal = journal
+		slf.mu.Unlock()
+	}()
+
+	for i := 1; ; i++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("journal record %v: %w", i, err)
+		}
+		if err := slf.replay(ctx, record); err != nil {
+			return fmt.Errorf("journal record %v: %w", i, err)
+		}
+	}
+}
+
+func (slf *Dostack) replay(ctx context.Context, record Record) error {
+	switch record.Kind {
+	case KindDo:
+		return slf.DoWith(ctx, record.Name, rawArgs(record.Args))
+	case KindGroup:
+		calls := []struct {
+			Name string
+			Args json.RawMessage
+		}{}
+		if raw, ok := record.Args.(json.RawMessage); ok {
+			if err := json.Unmarshal(raw, &calls); err != nil {
+				return err
+			}
+		}
+		group := make([]Call, 0, len(calls))
+		for _, v := range calls {
+			group = append(group, Call{v.Name, rawArgs(v.Args)})
+		}
+		return slf.DoGroup(ctx, record.Name, group...)
+	case KindUndo:
+		return slf.Undo(ctx)
+	case KindRedo:
+		return slf.Redo(ctx)
+	case KindCheckpoint:
+		return slf.Checkpoint(record.Name)
+	case KindSkip:
+		// Пропускается последнее действие с именем из записи: за ним в стеке могут находиться действия,
+		// завершившиеся между неудачным откатом и снятием блокировки.
+		slf.mu.Lock()
+		defer slf.mu.Unlock()
+		for i := len(slf.stack) - 1; i >= 0; i-- {
+			if slf.stack[i].name == record.Name {
+				return slf.skip(slf.stack[i])
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown record kind %q", record.Kind)
+	}
+}
+
+// rawArgs приводит аргументы из внешнего журнала к виду, принимаемому DoWith: отсутствие аргументов есть nil.
+func rawArgs(args any) any {
+	raw, ok := args.(json.RawMessage)
+	if !ok || len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
+
+// jsonJournal есть журнал в формате JSON Lines: одна запись на строку.
+type jsonJournal struct {
+	w   io.Writer
+	scn *bufio.Scanner
+}
+
+// jsonRecord есть представление записи в формате JSON Lines.
+type jsonRecord struct {
+	Kind      string          `json:"kind"`
+	Name      string          `json:"name"`
+	Args      json.RawMessage `json:"args,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// NewJSONJournal создаёт внешний журнал в формате JSON Lines.
+func NewJSONJournal(w io.Writer) JournalWriter { return &jsonJournal{w: w} }
+
+// ReadJSONJournal создаёт читателя журнала в формате JSON Lines.
+func ReadJSONJournal(r io.Reader) JournalReader { return &jsonJournal{scn: newScanner(r)} }
+
+func (slf *jsonJournal) Write(record Record) error {
+	args, err := json.Marshal(record.Args)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(jsonRecord{record.Kind, record.Name, args, record.Timestamp})
+	if err != nil {
+		return err
+	}
+	_, err = slf.w.Write(append(line, '\n'))
+	return err
+}
+
+func (slf *jsonJournal) Read() (Record, error) {
+	line, err := scan(slf.scn)
+	if err != nil {
+		return Record{}, err
+	}
+	record := jsonRecord{}
+	if err := json.Unmarshal(line, &record); err != nil {
+		return Record{}, err
+	}
+	return Record{record.Kind, record.Name, record.Args, record.Timestamp}, nil
+}
+
+// textJournal есть журнал в текстовом формате: одна запись на строку, поля разделены табуляцией —
+// время в формате RFC 3339, вид записи, имя действия в кавычках Go и аргументы в JSON.
+type textJournal struct {
+	w   io.Writer
+	scn *bufio.Scanner
+}
+
+// NewTextJournal создаёт внешний журнал в текстовом формате.
+func NewTextJournal(w io.Writer) JournalWriter { return &textJournal{w: w} }
+
+// ReadTextJournal создаёт читателя журнала в текстовом формате.
+func ReadTextJournal(r io.Reader) JournalReader { return &textJournal{scn: newScanner(r)} }
+
+func (slf *textJournal) Write(record Record) error {
+	args, err := json.Marshal(record.Args)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(
+		slf.w, "%v\t%v\t%v\t%s\n",
+		record.Timestamp.Format(time.RFC3339Nano), record.Kind, strconv.Quote(record.Name), args,
+	)
+	return err
+}
+
+func (slf *textJournal) Read() (Record, error) {
+	line, err := scan(slf.scn)
+	if err != nil {
+		return Record{}, err
+	}
+	fields := strings.SplitN(string(line), "\t", 4)
+	if len(fields) != 4 {
+		return Record{}, fmt.Errorf("malformed record")
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return Record{}, err
+	}
+	name, err := strconv.Unquote(fields[2])
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{fields[1], name, json.RawMessage(fields[3]), timestamp}, nil
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scn := bufio.NewScanner(r)
+	scn.Buffer(nil, 1<<20)
+	return scn
+}
+
+// scan возвращает следующую непустую строку или io.EOF по окончании ввода.
+func scan(scn *bufio.Scanner) ([]byte, error) {
+	for scn.Scan() {
+		if len(scn.Bytes()) != 0 {
+			return scn.Bytes(), nil
+		}
+	}
+	if err := scn.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,87 @@
+package dostack
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+	// newDostack создаёт хранилище с параметризованной командой изменения словаря.
+	newDostack := func(storage map[string]string, options ...Option) *Dostack {
+		return New(append(
+			options,
+			WithFactory(
+				"update",
+				func(args [2]string) (Doer, error) {
+					return &testSetter{storage: storage, key: args[0], value: args[1]}, nil
+				},
+				true,
+			),
+			WithFunc("noop", func() error { return nil }),
+		)...)
+	}
+
+	for name, format := range map[string]struct {
+		writer func(io.Writer) JournalWriter
+		reader func(io.Reader) JournalReader
+	}{
+		"json": {NewJSONJournal, ReadJSONJournal},
+		"text": {NewTextJournal, ReadTextJournal},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "journal")
+			f, err := OpenJournal(path)
+			assert.NoError(t, err)
+
+			storage := map[string]string{}
+			testDostack := newDostack(storage, WithJournal(format.writer(f)))
+			assert.NoError(t, testDostack.DoWith(ctx, "update", [2]string{"a", "1"}))
+			assert.NoError(t, testDostack.Do(ctx, "noop"))
+			assert.NoError(t, testDostack.DoGroup(ctx, "batch", Call{"update", [2]string{"b", "2"}}, Call{"update", [2]string{"c\t", "3"}}))
+			assert.NoError(t, testDostack.DoWith(ctx, "update", [2]string{"a", "4"}))
+			assert.NoError(t, testDostack.Undo(ctx))
+			assert.NoError(t, testDostack.Undo(ctx))
+			assert.NoError(t, testDostack.Redo(ctx))
+			assert.NoError(t, f.Close())
+
+			history := testDostack.History()
+			assert.Len(t, history, 7)
+			assert.Equal(t, KindGroup, history[2].Kind)
+			assert.Equal(t, "batch", history[2].Name)
+			assert.Equal(t, KindRedo, history[6].Kind)
+
+			// Восстанавливаем состояние в новом хранилище после "перезапуска".
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			replayed := map[string]string{}
+			replayedDostack := newDostack(replayed)
+			assert.NoError(t, replayedDostack.Replay(ctx, format.reader(bytes.NewReader(data))))
+			assert.Equal(t, storage, replayed)
+			assert.Equal(t, map[string]string{"a": "1", "b": "2", "c\t": "3"}, replayed)
+			assert.Len(t, replayedDostack.History(), len(history))
+			for i, v := range replayedDostack.History() {
+				assert.Equal(t, history[i].Kind, v.Kind)
+				assert.Equal(t, history[i].Name, v.Name)
+			}
+
+			// Восстановленный стек откатывается так же, как исходный.
+			for range 3 {
+				assert.NoError(t, replayedDostack.Undo(ctx))
+			}
+			assert.Empty(t, replayed)
+			assert.False(t, replayedDostack.CanUndo())
+		})
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		assert.Error(t, newDostack(map[string]string{}).Replay(ctx, ReadTextJournal(bytes.NewBufferString("garbage\n"))))
+		assert.Error(t, newDostack(map[string]string{}).Replay(ctx, ReadJSONJournal(bytes.NewBufferString(`{"kind":"do","name":"unknown"}`))))
+	})
+}
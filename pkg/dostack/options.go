@@ -1,5 +1,12 @@
 package dostack
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // Option предназначен для добавления свойств и команд в конструкторе.
 type Option func(*Dostack)
 
@@ -36,13 +43,142 @@ func WithFuncs(name string, do, undo func() error) Option {
 	}
 }
 
+// WithContextFuncs добавляет к списку действий функции действия и отката, поддерживающие отмену через контекст.
+// При отсутствии функции отката запрос отката действия невозможен.
+func WithContextFuncs(name string, do, undo func(ctx context.Context) error) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if do != nil {
+			d.commands[name] = &command{Doer: &contextDoer{do, undo}, undoable: undo != nil}
+		}
+	}
+}
+
+// WithTimeout ограничивает время исполнения и отката команды.
+// Прервать по истечении времени можно только исполнителя, реализующего ContextDoer.
+func WithTimeout(name string, timeout time.Duration) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.timeouts[name] = timeout
+	}
+}
+
+// WithFactory добавляет к списку действий параметризованную команду.
+// При каждом вызове фабрика создаёт нового исполнителя; аргументы должны иметь тип T,
+// а при их отсутствии фабрика получает нулевое значение T.
+// Флаг undoable есть признак того, что исполнители команды поддерживают откат.
+func WithFactory[T any](name string, factory CommandFactory[T], undoable bool) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if factory != nil {
+			d.commands[name] = &command{factory: typedFactory(factory), undoable: undoable}
+		}
+	}
+}
+
+// typedFactory приводит аргументы вызова к типу, ожидаемому фабрикой, и возвращает их вместе с исполнителем,
+// чтобы в журнал попадали аргументы типа T, а не их исходное представление.
+// Аргументы в виде json.RawMessage, полученные из внешнего журнала, декодируются в T.
+// Так же декодируются строковые аргументы, если T не является строкой, например при вводе из командной строки.
+func typedFactory[T any](factory CommandFactory[T]) func(args any) (Doer, any, error) {
+	return func(args any) (Doer, any, error) {
+		var typed T
+		switch v := args.(type) {
+		case nil:
+		case json.RawMessage:
+			if err := json.Unmarshal(v, &typed); err != nil {
+				return nil, nil, fmt.Errorf("unexpected arguments: %w", err)
+			}
+		case T:
+			typed = v
+		case string:
+			if err := json.Unmarshal([]byte(v), &typed); err != nil {
+				return nil, nil, fmt.Errorf("unexpected arguments: %w", err)
+			}
+		default:
+			return nil, nil, fmt.Errorf("unexpected arguments type %T", args)
+		}
+		doer, err := factory(typed)
+		return doer, typed, err
+	}
+}
+
+// WithGroup добавляет к списку действий группу команд, исполняемую как единое целое (макрокоманду).
+// Исполнители команд группы создаются заново при каждом вызове.
+func WithGroup(name string, calls ...Call) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.commands[name] = &command{
+			factory: func(args any) (Doer, any, error) {
+				if args != nil {
+					return nil, nil, fmt.Errorf("group does not accept arguments")
+				}
+				g, err := d.group(calls)
+				return g, nil, err
+			},
+			undoable: true,
+		}
+	}
+}
+
+// WithJournal подключает внешний журнал, в который дописываются записи о совершённых действиях и откатах.
+func WithJournal(journal JournalWriter) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.journal = journal
+	}
+}
+
+// WithMaxDepth ограничивает глубину стека. Действия, вышедшие за её пределы, вытесняются из основания стека
+// и больше не могут быть откатаны.
+func WithMaxDepth(depth int) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.maxDepth = depth
+	}
+}
+
+// WithMaxHistory ограничивает длину журнала, возвращаемого History: сохраняются только последние записи.
+// Внешний журнал не ограничивается.
+func WithMaxHistory(length int) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.maxLog = length
+	}
+}
+
+// WithUndoPolicy задаёт политику обработки неудачного отката.
+func WithUndoPolicy(policy UndoPolicy) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.policy = policy
+	}
+}
+
 // WithExplicitUndo добавляет к списку действий явное именованное применение отката.
+// Служебная команда не попадает в стек и журнал.
 func WithExplicitUndo(name string) Option {
-	return func(d *Dostack) { d.AddFunc(name, d.explicitUndo) }
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.commands[name] = &command{Doer: &contextDoer{do: d.Undo}, service: true}
+	}
 }
 
-func (slf *Dostack) explicitUndo() error {
-	slf.mu.Unlock()
-	defer slf.mu.Lock()
-	return slf.Undo()
+// WithExplicitRedo добавляет к списку действий явное именованное применение повтора.
+// Служебная команда не попадает в стек и журнал.
+func WithExplicitRedo(name string) Option {
+	return func(d *Dostack) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.commands[name] = &command{Doer: &contextDoer{do: d.Redo}, service: true}
+	}
 }
@@ -0,0 +1,74 @@
+package dostack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	FailureKeep   = "keep"   // Действие остаётся в стеке, откат можно запросить повторно.
+	FailureSkip   = "skip"   // Действие удаляется из стека без отката, пропуск записывается в журнал.
+	FailurePoison = "poison" // Хранилище блокируется до вмешательства оператора через Unpoison.
+)
+
+// ErrPoisoned возвращается любой операцией над хранилищем, заблокированным после неудачного отката.
+var ErrPoisoned = errors.New("dostack is poisoned")
+
+// UndoPolicy есть политика обработки неудачного отката.
+type UndoPolicy struct {
+	Attempts  int           // Число попыток отката; нулевое значение означает одну попытку.
+	Backoff   time.Duration // Пауза между попытками.
+	OnFailure string        // Поведение после исчерпания попыток; по умолчанию FailureKeep.
+}
+
+// UndoError есть ошибка отката действия.
+type UndoError struct {
+	Name     string    // Имя действия.
+	DoneAt   time.Time // Время исполнения откатываемого действия.
+	Attempts int       // Число совершённых попыток отката.
+	Err      error     // Ошибка последней попытки.
+}
+
+func (slf *UndoError) Error() string {
+	return fmt.Sprintf(
+		"command <%v> done at %v undo failure after %v attempt(s): %v",
+		slf.Name, slf.DoneAt.Format(time.RFC3339Nano), slf.Attempts, slf.Err,
+	)
+}
+
+func (slf *UndoError) Unwrap() error { return slf.Err }
+
+// undo откатывает действие с повторными попытками согласно политике.
+// Ожидание между попытками прерывается отменой контекста.
+func (slf UndoPolicy) undo(ctx context.Context, e *entry) *UndoError {
+	attempts := max(slf.Attempts, 1)
+	for i := 1; ; i++ {
+		err := e.run(ctx, true)
+		if err == nil {
+			return nil
+		}
+		if i == attempts {
+			return &UndoError{Name: e.name, DoneAt: e.doneAt, Attempts: i, Err: err}
+		}
+		select {
+		case <-ctx.Done():
+			return &UndoError{Name: e.name, DoneAt: e.doneAt, Attempts: i, Err: errors.Join(err, ctx.Err())}
+		case <-time.After(slf.Backoff):
+		}
+	}
+}
+
+// CheckpointError есть ошибка возврата к отметке: откат прерван после Undone успешно откатанных действий.
+type CheckpointError struct {
+	Name   string // Имя отметки.
+	Undone int    // Число действий, откатанных до неудачи.
+	Err    error  // Ошибка неудачного отката.
+}
+
+func (slf *CheckpointError) Error() string {
+	return fmt.Sprintf("checkpoint <%v> rollback stopped after %v undone command(s): %v", slf.Name, slf.Undone, slf.Err)
+}
+
+func (slf *CheckpointError) Unwrap() error { return slf.Err }
@@ -0,0 +1,16 @@
+package shell
+
+import "io"
+
+// Option предназначен для настройки оболочки в конструкторе.
+type Option func(*Shell)
+
+// WithPrompt задаёт приглашение к вводу.
+func WithPrompt(prompt string) Option {
+	return func(s *Shell) { s.prompt = prompt }
+}
+
+// WithOutput задаёт вывод справки, истории и ошибок.
+func WithOutput(out io.Writer) Option {
+	return func(s *Shell) { s.out = out }
+}
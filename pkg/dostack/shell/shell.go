@@ -0,0 +1,188 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"licklib/pkg/dostack"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrExit возвращается Exec по команде exit.
+var ErrExit = errors.New("exit")
+
+// builtin есть встроенная команда оболочки.
+type builtin struct {
+	usage string                                       // Описание для справки.
+	run   func(ctx context.Context, args string) error // Исполнение команды с аргументами из строки.
+}
+
+// Shell есть командная оболочка над хранилищем команд.
+// Строка ввода состоит из имени команды и необязательных аргументов через пробел.
+// Встроенные команды help, history, undo, redo и exit имеют приоритет над зарегистрированными.
+type Shell struct {
+	ds       *dostack.Dostack
+	prompt   string             // Приглашение к вводу.
+	out      io.Writer          // Вывод справки, истории и ошибок.
+	builtins map[string]builtin // Встроенные команды.
+}
+
+// New создаёт оболочку над хранилищем.
+func New(ds *dostack.Dostack, options ...Option) *Shell {
+	shell := &Shell{ds: ds, prompt: "> ", out: os.Stdout}
+	shell.builtins = map[string]builtin{
+		"help":    {"show this help", shell.help},
+		"history": {"show executed commands and undos", shell.history},
+		"undo":    {"undo the last command", func(ctx context.Context, _ string) error { return ds.Undo(ctx) }},
+		"redo":    {"redo the last undone command", func(ctx context.Context, _ string) error { return ds.Redo(ctx) }},
+		"exit":    {"leave the shell", func(context.Context, string) error { return ErrExit }},
+	}
+	for _, v := range options {
+		v(shell)
+	}
+	return shell
+}
+
+// Exec исполняет строку ввода. Пустые строки и комментарии, начинающиеся с #, пропускаются.
+func (slf *Shell) Exec(ctx context.Context, line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	name, args, _ := strings.Cut(line, " ")
+	args = strings.TrimSpace(args)
+	if b, ok := slf.builtins[name]; ok {
+		return b.run(ctx, args)
+	}
+	if args == "" {
+		return slf.ds.Do(ctx, name)
+	}
+	return slf.ds.DoWith(ctx, name, args)
+}
+
+// Run исполняет команды, вводимые построчно, до команды exit или окончания ввода.
+// Ошибки команд выводятся и не прерывают работу оболочки.
+// Строка, оканчивающаяся табуляцией, не исполняется: вместо этого выводятся варианты дополнения её начала.
+// Так дополнение доступно и в терминале без построчного редактора: префикс, Tab, Enter.
+func (slf *Shell) Run(ctx context.Context, in io.Reader) error {
+	scn := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(slf.out, slf.prompt)
+		if !scn.Scan() {
+			return scn.Err()
+		}
+		if prefix, ok := strings.CutSuffix(scn.Text(), "\t"); ok {
+			fmt.Fprintln(slf.out, strings.Join(slf.Complete(strings.TrimSpace(prefix)), " "))
+			continue
+		}
+		err := slf.Exec(ctx, scn.Text())
+		if errors.Is(err, ErrExit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(slf.out, "error: %v\n", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// RunScript исполняет сценарий построчно и останавливается на первой ошибке, сообщая номер строки.
+// Команда exit завершает сценарий без ошибки.
+func (slf *Shell) RunScript(ctx context.Context, script io.Reader) error {
+	scn := bufio.NewScanner(script)
+	for i := 1; scn.Scan(); i++ {
+		err := slf.Exec(ctx, scn.Text())
+		if errors.Is(err, ErrExit) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("line %v: %w", i, err)
+		}
+	}
+	return scn.Err()
+}
+
+// RunFile исполняет сценарий из файла.
+func (slf *Shell) RunFile(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return slf.RunScript(ctx, f)
+}
+
+// Complete возвращает упорядоченные имена встроенных и зарегистрированных команд, начинающиеся с prefix.
+// Используется Run для строк, оканчивающихся табуляцией, и может быть передан построчному редактору ввода
+// в качестве функции дополнения.
+func (slf *Shell) Complete(prefix string) []string {
+	var names []string
+	for name := range slf.builtins {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	for _, v := range slf.ds.Commands() {
+		if strings.HasPrefix(v.Name, prefix) && !slices.Contains(names, v.Name) {
+			names = append(names, v.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// help выводит справку по встроенным и зарегистрированным командам.
+func (slf *Shell) help(context.Context, string) error {
+	names := make([]string, 0, len(slf.builtins))
+	for name := range slf.builtins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	fmt.Fprintln(slf.out, "builtin commands:")
+	for _, name := range names {
+		fmt.Fprintf(slf.out, "  %-12v %v\n", name, slf.builtins[name].usage)
+	}
+	fmt.Fprintln(slf.out, "commands:")
+	for _, v := range slf.ds.Commands() {
+		if _, ok := slf.builtins[v.Name]; ok {
+			continue
+		}
+		var traits []string
+		if v.Parameterized {
+			traits = append(traits, "accepts arguments")
+		}
+		if v.Undoable {
+			traits = append(traits, "undoable")
+		}
+		fmt.Fprintln(slf.out, strings.TrimRight(fmt.Sprintf("  %-12v %v", v.Name, strings.Join(traits, ", ")), " "))
+	}
+	return nil
+}
+
+// history выводит журнал совершённых действий и откатов.
+func (slf *Shell) history(context.Context, string) error {
+	for _, v := range slf.ds.History() {
+		line := fmt.Sprintf("%v %-5v %v", v.Timestamp.Local().Format(time.TimeOnly), v.Kind, v.Name)
+		if text, ok := v.Args.(string); ok {
+			line += " " + text
+		} else if v.Args != nil {
+			args, err := json.Marshal(v.Args)
+			if err != nil {
+				return err
+			}
+			line += " " + string(args)
+		}
+		fmt.Fprintln(slf.out, line)
+	}
+	return nil
+}
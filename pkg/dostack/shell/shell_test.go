@@ -0,0 +1,113 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"licklib/pkg/dostack"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell(t *testing.T) {
+	ctx := context.Background()
+	var i int
+	newShell := func(out *bytes.Buffer, options ...dostack.Option) *Shell {
+		i = 0
+		ds := dostack.New(append(options,
+			dostack.WithFuncs(
+				"inc",
+				func() error {
+					i++
+					return nil
+				},
+				func() error {
+					i--
+					return nil
+				},
+			),
+			dostack.WithFactory(
+				"add",
+				func(n int) (dostack.Doer, error) {
+					return &adder{&i, n}, nil
+				},
+				true,
+			),
+			dostack.WithFunc("info", func() error { return nil }),
+		)...)
+		return New(ds, WithOutput(out), WithPrompt(""))
+	}
+
+	t.Run("run", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		shell := newShell(out)
+		input := "inc\n\n# comment\nadd 5\nunknown\nundo\nredo\nadd oops\nhistory\nhelp\nexit\ninc\n"
+		assert.NoError(t, shell.Run(ctx, strings.NewReader(input)))
+		assert.Equal(t, 6, i)
+
+		assert.Contains(t, out.String(), "error: command <unknown> is not registered")
+		assert.Contains(t, out.String(), "do    add 5")
+		assert.Contains(t, out.String(), "redo  add 5")
+		assert.Contains(t, out.String(), "accepts arguments, undoable")
+		assert.Equal(t, 2, strings.Count(out.String(), "error:"))
+	})
+
+	t.Run("script", func(t *testing.T) {
+		shell := newShell(&bytes.Buffer{})
+		path := filepath.Join(t.TempDir(), "script")
+		assert.NoError(t, os.WriteFile(path, []byte("inc\nadd 2\n# stop here\nadd x\ninc\n"), 0o644))
+		err := shell.RunFile(ctx, path)
+		assert.ErrorContains(t, err, "line 4:")
+		assert.Equal(t, 3, i)
+
+		assert.NoError(t, shell.RunScript(ctx, strings.NewReader("undo\nundo\nexit\ninc\n")))
+		assert.Zero(t, i)
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		shell := newShell(&bytes.Buffer{})
+		assert.Equal(t, []string{"inc", "info"}, shell.Complete("in"))
+		assert.Equal(t, []string{"help", "history"}, shell.Complete("h"))
+		assert.Empty(t, shell.Complete("z"))
+		assert.Len(t, shell.Complete(""), 8)
+
+		// Строка, оканчивающаяся табуляцией, выводит варианты и не исполняется.
+		out := &bytes.Buffer{}
+		shell = newShell(out)
+		assert.NoError(t, shell.Run(ctx, strings.NewReader("in\t\ninc\t\n")))
+		assert.Equal(t, "inc info\ninc\n", out.String())
+		assert.Zero(t, i)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		// Журнал, записанный оболочкой, восстанавливается в новом хранилище.
+		journal := &bytes.Buffer{}
+		shell := newShell(&bytes.Buffer{}, dostack.WithJournal(dostack.NewJSONJournal(journal)))
+		assert.NoError(t, shell.RunScript(ctx, strings.NewReader("add 5\ninc\nadd 2\nundo\n")))
+		assert.Equal(t, 6, i)
+
+		shell = newShell(&bytes.Buffer{})
+		assert.NoError(t, shell.ds.Replay(ctx, dostack.ReadJSONJournal(journal)))
+		assert.Equal(t, 6, i)
+		assert.NoError(t, shell.ds.Redo(ctx))
+		assert.Equal(t, 8, i)
+	})
+}
+
+type adder struct {
+	i *int
+	n int
+}
+
+func (slf *adder) Do() error {
+	*slf.i += slf.n
+	return nil
+}
+
+func (slf *adder) Undo() error {
+	*slf.i -= slf.n
+	return nil
+}
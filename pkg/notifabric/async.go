@@ -0,0 +1,191 @@
+package notifabric
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed возвращается при отправке уведомления через закрытый уведомитель или записи в закрытый файл.
+var ErrClosed = errors.New("notifier is closed")
+
+// AsyncOption предназначен для настройки асинхронного уведомителя в конструкторе.
+type AsyncOption func(*AsyncNotifier)
+
+// WithQueue задаёт вместимость очереди уведомлений.
+func WithQueue(capacity int) AsyncOption {
+	return func(a *AsyncNotifier) { a.capacity = capacity }
+}
+
+// WithWorkers задаёт число исполнителей, отправляющих уведомления из очереди.
+func WithWorkers(workers int) AsyncOption {
+	return func(a *AsyncNotifier) { a.workers = max(workers, 1) }
+}
+
+// WithRetry задаёт число попыток отправки и паузу между ними; пауза удваивается с каждой попыткой.
+// Ошибки, для которых Retryable ложно, не повторяются.
+func WithRetry(attempts int, backoff time.Duration) AsyncOption {
+	return func(a *AsyncNotifier) { a.attempts, a.backoff = max(attempts, 1), backoff }
+}
+
+// WithDeadLetter задаёт получателя уведомлений, отправка которых не удалась после всех попыток.
+func WithDeadLetter(deadLetter Notifier) AsyncOption {
+	return func(a *AsyncNotifier) { a.deadLetter = deadLetter }
+}
+
+// AsyncNotifier есть асинхронный уведомитель: уведомления кладутся в ограниченную очередь
+// и отправляются исполнителями в фоне с повторными попытками.
+type AsyncNotifier struct {
+	target     Notifier
+	deadLetter Notifier      // Получатель неотправленных уведомлений; может отсутствовать.
+	capacity   int           // Вместимость очереди.
+	workers    int           // Число исполнителей.
+	attempts   int           // Число попыток отправки.
+	backoff    time.Duration // Начальная пауза между попытками.
+
+	queue   chan *Notification
+	mu      sync.Mutex
+	idle    *sync.Cond         // Сигнал об отправке всех принятых уведомлений.
+	pending int                // Число принятых, но ещё не обработанных уведомлений.
+	closed  bool               // Флаг того, что новые уведомления не принимаются.
+	ctx     context.Context    // Контекст отправки; отменяется, когда отправку следует прервать.
+	cancel  context.CancelFunc // Отмена контекста отправки.
+	wg      sync.WaitGroup     // Ожидание завершения исполнителей.
+}
+
+// Async создаёт асинхронный уведомитель и запускает его исполнителей.
+// По умолчанию очередь вмещает 100 уведомлений, исполнитель один, а отправка производится однократно.
+func Async(target Notifier, options ...AsyncOption) *AsyncNotifier {
+	async := &AsyncNotifier{target: target, capacity: 100, workers: 1, attempts: 1}
+	for _, v := range options {
+		v(async)
+	}
+	async.idle = sync.NewCond(&async.mu)
+	async.queue = make(chan *Notification, max(async.capacity, 1))
+	async.ctx, async.cancel = context.WithCancel(context.Background())
+
+	async.wg.Add(async.workers)
+	for range async.workers {
+		go async.work()
+	}
+	return async
+}
+
+// Notify кладёт уведомление в очередь. При заполнении очереди вызов ожидает освобождения места.
+func (slf *AsyncNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+// NotifyContext кладёт уведомление в очередь. При заполнении очереди вызов ожидает освобождения места
+// не дольше, чем до отмены контекста или остановки уведомителя.
+func (slf *AsyncNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	slf.mu.Lock()
+	if slf.closed {
+		slf.mu.Unlock()
+		return ErrClosed
+	}
+	slf.pending++
+	slf.mu.Unlock()
+
+	select {
+	case slf.queue <- n:
+		return nil
+	case <-ctx.Done():
+		slf.done()
+		return ctx.Err()
+	case <-slf.ctx.Done():
+		slf.done()
+		return ErrClosed
+	}
+}
+
+// Flush ожидает обработки всех принятых уведомлений или отмены контекста.
+func (slf *AsyncNotifier) Flush(ctx context.Context) error {
+	// Отмена контекста будит ожидание, чтобы оно не пережило вызов.
+	stop := context.AfterFunc(ctx, func() {
+		slf.mu.Lock()
+		defer slf.mu.Unlock()
+		slf.idle.Broadcast()
+	})
+	defer stop()
+
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	for slf.pending > 0 && ctx.Err() == nil {
+		slf.idle.Wait()
+	}
+	if slf.pending > 0 {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Close прекращает приём уведомлений, ожидает обработки принятых и останавливает исполнителей.
+// При отмене контекста текущие отправки и повторные попытки прерываются, а оставшиеся в очереди уведомления отбрасываются.
+func (slf *AsyncNotifier) Close(ctx context.Context) error {
+	slf.mu.Lock()
+	slf.closed = true
+	slf.mu.Unlock()
+
+	err := slf.Flush(ctx)
+	slf.cancel()
+	slf.wg.Wait()
+
+	// Отброшенные уведомления больше не ожидаются.
+	slf.mu.Lock()
+	slf.pending = 0
+	slf.idle.Broadcast()
+	slf.mu.Unlock()
+	return err
+}
+
+func (slf *AsyncNotifier) work() {
+	defer slf.wg.Done()
+	for {
+		var n *Notification
+		select {
+		case <-slf.ctx.Done():
+			return
+		case n = <-slf.queue:
+		}
+		// После остановки оставшиеся в очереди уведомления отбрасываются.
+		if slf.ctx.Err() != nil {
+			return
+		}
+		if err := slf.deliver(n); err != nil && slf.deadLetter != nil {
+			_ = slf.deadLetter.Notify(n)
+		}
+		slf.done()
+	}
+}
+
+// deliver отправляет уведомление с повторными попытками до остановки уведомителя.
+func (slf *AsyncNotifier) deliver(n *Notification) error {
+	backoff := slf.backoff
+	for i := 1; ; i++ {
+		err := slf.target.NotifyContext(slf.ctx, n)
+		if err == nil || i >= slf.attempts || !Retryable(err) {
+			return err
+		}
+		select {
+		case <-slf.ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (slf *AsyncNotifier) done() {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	// После закрытия счётчик мог быть сброшен раньше, чем отказ в постановке в очередь.
+	slf.pending = max(slf.pending-1, 0)
+	if slf.pending == 0 {
+		slf.idle.Broadcast()
+	}
+}
@@ -0,0 +1,94 @@
+package notifabric
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("flush", func(t *testing.T) {
+		target, deadLetter := &testRecorder{failures: 2}, &testRecorder{}
+		async := Async(target, WithQueue(4), WithWorkers(1), WithRetry(2, time.Millisecond), WithDeadLetter(deadLetter))
+		for i := range 10 {
+			assert.NoError(t, async.Notify(Text(fmt.Sprint(i))))
+		}
+		assert.NoError(t, async.Flush(ctx))
+
+		// Первое уведомление исчерпало обе попытки, остальные доставлены по порядку.
+		assert.Equal(t, []string{"0"}, deadLetter.bodies)
+		assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}, target.bodies)
+
+		assert.NoError(t, async.Close(ctx))
+		assert.ErrorIs(t, async.Notify(Text("late")), ErrClosed)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		target := &testRecorder{}
+		async := Async(target, WithWorkers(4))
+		for i := range 100 {
+			assert.NoError(t, async.Notify(Text(fmt.Sprint(i))))
+		}
+		assert.NoError(t, async.Close(ctx))
+		assert.Len(t, target.bodies, 100)
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		// Постоянная ошибка сразу передаётся в очередь недоставленных без повторов.
+		target, deadLetter := &testRecorder{failures: 5, err: Permanent(&StatusError{400})}, &testRecorder{}
+		async := Async(target, WithRetry(5, time.Hour), WithDeadLetter(deadLetter))
+		assert.NoError(t, async.Notify(Text("rejected")))
+		assert.NoError(t, async.Close(ctx))
+		assert.Equal(t, 1, target.attempts)
+		assert.Equal(t, []string{"rejected"}, deadLetter.bodies)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		target, deadLetter := &testRecorder{failures: 1_000}, &testRecorder{}
+		async := Async(target, WithRetry(1_000, time.Hour), WithDeadLetter(deadLetter))
+		assert.NoError(t, async.Notify(Text("stuck")))
+		assert.NoError(t, async.Notify(Text("dropped")))
+
+		timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, async.Close(timeout), context.DeadlineExceeded)
+		assert.Equal(t, []string{"stuck"}, deadLetter.bodies)
+		assert.NoError(t, async.Flush(ctx))
+	})
+
+	t.Run("context", func(t *testing.T) {
+		started, deadLetter := make(chan struct{}, 1), &testRecorder{}
+		async := Async(blockingNotifier(started), WithQueue(1), WithDeadLetter(deadLetter))
+		assert.NoError(t, async.Notify(Text("stuck")))
+		<-started
+		assert.NoError(t, async.Notify(Text("queued")))
+
+		// Постановка в заполненную очередь и ожидание обработки прерываются отменой контекста.
+		timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, async.NotifyContext(timeout, Text("overflow")), context.DeadlineExceeded)
+		assert.ErrorIs(t, async.Flush(timeout), context.DeadlineExceeded)
+
+		// Закрытие по истечении контекста прерывает зависшую отправку.
+		assert.ErrorIs(t, async.Close(timeout), context.DeadlineExceeded)
+		assert.Equal(t, []string{"stuck"}, deadLetter.bodies)
+	})
+}
+
+// blockingNotifier сообщает о начале отправки и ожидает отмены её контекста.
+type blockingNotifier chan struct{}
+
+func (slf blockingNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf blockingNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	slf <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
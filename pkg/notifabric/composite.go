@@ -0,0 +1,105 @@
+package notifabric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Broadcast создаёт уведомитель, отправляющий уведомление всем получателям одновременно.
+// Ошибки получателей объединяются в порядке их перечисления.
+func Broadcast(targets ...Notifier) Notifier { return &broadcast{targets} }
+
+type broadcast struct{ targets []Notifier }
+
+func (slf *broadcast) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *broadcast) NotifyContext(ctx context.Context, n *Notification) error {
+	errs := make([]error, len(slf.targets))
+	var wg sync.WaitGroup
+	for i, v := range slf.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := v.NotifyContext(ctx, n); err != nil {
+				errs[i] = fmt.Errorf("target %v: %w", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Failover создаёт уведомитель, перебирающий получателей по порядку до первой успешной отправки.
+// Если отправка не удалась ни одному получателю, возвращаются ошибки всех получателей.
+// При отмене контекста перебор прекращается с ошибкой контекста.
+func Failover(targets ...Notifier) Notifier { return &failover{targets} }
+
+type failover struct{ targets []Notifier }
+
+func (slf *failover) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *failover) NotifyContext(ctx context.Context, n *Notification) error {
+	errs := make([]error, 0, len(slf.targets))
+	for i, v := range slf.targets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := v.NotifyContext(ctx, n)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("target %v: %w", i, err))
+	}
+	return errors.Join(errs...)
+}
+
+// Route есть маршрут уведомлений: условие и получатель.
+type Route struct {
+	Match  func(n *Notification) bool
+	Target Notifier
+}
+
+// MinSeverity есть условие маршрута: уровень важности не ниже заданного.
+func MinSeverity(severity Severity) func(n *Notification) bool {
+	return func(n *Notification) bool { return n.Severity >= severity }
+}
+
+// HasLabel есть условие маршрута: метка с заданным значением.
+func HasLabel(key, value string) func(n *Notification) bool {
+	return func(n *Notification) bool {
+		v, ok := n.Labels[key]
+		return ok && v == value
+	}
+}
+
+// Router создаёт уведомитель, отправляющий уведомление по первому подходящему маршруту.
+// Уведомление, не подошедшее ни одному маршруту, отправляется запасному получателю,
+// а при его отсутствии отправка завершается ошибкой.
+func Router(fallback Notifier, routes ...Route) Notifier { return &router{fallback, routes} }
+
+type router struct {
+	fallback Notifier
+	routes   []Route
+}
+
+func (slf *router) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *router) NotifyContext(ctx context.Context, n *Notification) error {
+	for _, v := range slf.routes {
+		if v.Match(n) {
+			return v.Target.NotifyContext(ctx, n)
+		}
+	}
+	if slf.fallback == nil {
+		return fmt.Errorf("no route for notification <%v>", n.Title)
+	}
+	return slf.fallback.NotifyContext(ctx, n)
+}
@@ -0,0 +1,91 @@
+package notifabric
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRecorder struct {
+	mu       sync.Mutex
+	bodies   []string
+	failures int
+	err      error // Ошибка отказа; по умолчанию временная.
+	attempts int
+}
+
+func (slf *testRecorder) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *testRecorder) NotifyContext(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	slf.attempts++
+	if slf.failures > 0 {
+		slf.failures--
+		if slf.err != nil {
+			return slf.err
+		}
+		return errors.New("failure")
+	}
+	slf.bodies = append(slf.bodies, n.Body)
+	return nil
+}
+
+func TestComposite(t *testing.T) {
+	t.Run("broadcast", func(t *testing.T) {
+		a, b, c := &testRecorder{}, &testRecorder{failures: 1}, &testRecorder{}
+		err := Broadcast(a, b, c).Notify(Text("first"))
+		assert.ErrorContains(t, err, "target 1: failure")
+		assert.NoError(t, Broadcast(a, b, c).Notify(Text("second")))
+		assert.Equal(t, []string{"first", "second"}, a.bodies)
+		assert.Equal(t, []string{"second"}, b.bodies)
+		assert.Equal(t, []string{"first", "second"}, c.bodies)
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		a, b, c := &testRecorder{failures: 2}, &testRecorder{failures: 1}, &testRecorder{}
+		notifier := Failover(a, b, c)
+		assert.NoError(t, notifier.Notify(Text("first")))
+		assert.NoError(t, notifier.Notify(Text("second")))
+		assert.Empty(t, a.bodies)
+		assert.Equal(t, []string{"second"}, b.bodies)
+		assert.Equal(t, []string{"first"}, c.bodies)
+
+		err := Failover(&testRecorder{failures: 1}, &testRecorder{failures: 1}).Notify(Text("third"))
+		assert.ErrorContains(t, err, "target 0: failure")
+		assert.ErrorContains(t, err, "target 1: failure")
+
+		// После истечения контекста следующие получатели не перебираются.
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err = Failover(blockingNotifier(make(chan struct{}, 1)), c).NotifyContext(ctx, Text("late"))
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, []string{"first"}, c.bodies)
+	})
+
+	t.Run("router", func(t *testing.T) {
+		pager, db, rest := &testRecorder{}, &testRecorder{}, &testRecorder{}
+		notifier := Router(
+			rest,
+			Route{MinSeverity(Critical), pager},
+			Route{HasLabel("team", "db"), db},
+		)
+		assert.NoError(t, notifier.Notify(&Notification{Body: "down", Severity: Critical, Labels: map[string]string{"team": "db"}}))
+		assert.NoError(t, notifier.Notify(&Notification{Body: "slow", Severity: Warning, Labels: map[string]string{"team": "db"}}))
+		assert.NoError(t, notifier.Notify(&Notification{Body: "deployed", Severity: Info}))
+		assert.Equal(t, []string{"down"}, pager.bodies)
+		assert.Equal(t, []string{"slow"}, db.bodies)
+		assert.Equal(t, []string{"deployed"}, rest.bodies)
+
+		assert.Error(t, Router(nil, Route{MinSeverity(Error), pager}).Notify(Text("dropped")))
+	})
+}
@@ -0,0 +1,57 @@
+package notifabric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StatusError есть ошибка отправки, вызванная неуспешным кодом ответа HTTP.
+type StatusError struct{ Code int }
+
+func (slf *StatusError) Error() string { return fmt.Sprintf("unexpected status code %v", slf.Code) }
+
+// permanentError есть ошибка, повтор отправки после которой бесполезен.
+type permanentError struct{ err error }
+
+func (slf *permanentError) Error() string { return slf.err.Error() }
+func (slf *permanentError) Unwrap() error { return slf.err }
+
+// Permanent помечает ошибку как постоянную: повторная отправка уведомления её не исправит.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// Retryable сообщает, имеет ли смысл повторить отправку после ошибки.
+// Не повторяются постоянные ошибки, ответы HTTP с кодами, отличными от 5xx, и отправки, отменённые через контекст.
+// Сетевые ошибки, истечение времени и прочие ошибки считаются временными.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if permanent := (*permanentError)(nil); errors.As(err, &permanent) {
+		return false
+	}
+	if status := (*StatusError)(nil); errors.As(err, &status) {
+		return status.Code >= 500
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// Duration есть промежуток времени, задаваемый в конфигурации строкой, например "5s" или "1m30s".
+type Duration time.Duration
+
+func (slf Duration) MarshalText() ([]byte, error) { return []byte(time.Duration(slf).String()), nil }
+
+func (slf *Duration) UnmarshalText(text []byte) error {
+	d, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*slf = Duration(d)
+	return nil
+}
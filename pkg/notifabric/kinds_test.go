@@ -0,0 +1,217 @@
+package notifabric
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMTP(t *testing.T) {
+	// Подставной SMTP-сервер принимает одно письмо и передаёт его текст.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost\r\n")
+		data := &strings.Builder{}
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+			case "EHLO":
+				fmt.Fprint(conn, "250-localhost\r\n250 AUTH PLAIN\r\n")
+			case "AUTH":
+				fmt.Fprint(conn, "235 authenticated\r\n")
+			case "MAIL", "RCPT":
+				data.WriteString(line)
+				fmt.Fprint(conn, "250 ok\r\n")
+			case "DATA":
+				fmt.Fprint(conn, "354 go ahead\r\n")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				fmt.Fprint(conn, "250 ok\r\n")
+			case "QUIT":
+				fmt.Fprint(conn, "221 bye\r\n")
+				received <- data.String()
+				return
+			default:
+				fmt.Fprint(conn, "250 ok\r\n")
+			}
+		}
+	}()
+
+	notifier, err := Default.Create(SMTP, SMTPConfig{
+		Address:  l.Addr().String(),
+		From:     "alerts@example.com",
+		To:       []string{"ops@example.com", "dev@example.com"},
+		Username: "user",
+		Password: "password",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(&Notification{Title: "disk", Body: "line 1\nline 2", Severity: Error}))
+
+	select {
+	case data := <-received:
+		assert.Contains(t, data, "MAIL FROM:<alerts@example.com>")
+		assert.Contains(t, data, "RCPT TO:<dev@example.com>")
+		assert.Contains(t, data, "Subject: [error] disk\r\n")
+		assert.Contains(t, data, "line 1\r\nline 2\r\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("mail is not received")
+	}
+
+	_, err = Default.Create(SMTP, SMTPConfig{Address: l.Addr().String()})
+	assert.Error(t, err)
+
+	// Сервер, не отвечающий на подключение, не задерживает отправку дольше ограничения времени.
+	stalled, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer stalled.Close()
+	notifier, err = Default.Create(SMTP, SMTPConfig{
+		Address: stalled.Addr().String(),
+		From:    "alerts@example.com",
+		To:      []string{"ops@example.com"},
+		Timeout: Duration(50 * time.Millisecond),
+	})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, notifier.Notify(Text("stuck")), os.ErrDeadlineExceeded)
+}
+
+func TestSyslog(t *testing.T) {
+	n := &Notification{
+		Title:     "disk",
+		Body:      "full",
+		Severity:  Critical,
+		Labels:    map[string]string{"host": `db"1]`, "zone": "a"},
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	expected := fmt.Sprintf(
+		`<130>1 2024-01-02T03:04:05Z node app %d - [labels@32473 host="db\"1\]" zone="a"] disk: full`,
+		os.Getpid(),
+	)
+
+	t.Run("udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		notifier, err := Default.Create(Syslog, SyslogConfig{Address: conn.LocalAddr().String(), Hostname: "node", AppName: "app", Facility: 16})
+		assert.NoError(t, err)
+		assert.NoError(t, notifier.Notify(n))
+
+		buf := make([]byte, 1024)
+		assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		size, _, err := conn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(buf[:size]))
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer l.Close()
+
+		notifier, err := Default.Create(Syslog, SyslogConfig{Network: "tcp", Address: l.Addr().String(), Hostname: "node", AppName: "app", Facility: 16})
+		assert.NoError(t, err)
+		assert.NoError(t, notifier.Notify(n))
+		assert.NoError(t, notifier.Notify(&Notification{Body: "second", Severity: Debug}))
+
+		conn, err := l.Accept()
+		assert.NoError(t, err)
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		// Сообщения разделены префиксом длины.
+		for _, want := range []string{expected, "<135>1"} {
+			prefix, err := r.ReadString(' ')
+			assert.NoError(t, err)
+			size, err := strconv.Atoi(strings.TrimSpace(prefix))
+			assert.NoError(t, err)
+			msg := make([]byte, size)
+			_, err = io.ReadFull(r, msg)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(msg), want), string(msg))
+		}
+
+		// Закрытие уведомителя закрывает соединение.
+		assert.NoError(t, notifier.(io.Closer).Close())
+		_, err = r.ReadByte()
+		assert.ErrorIs(t, err, io.EOF)
+		assert.ErrorIs(t, notifier.Notify(n), ErrClosed)
+		assert.ErrorIs(t, notifier.(io.Closer).Close(), ErrClosed)
+	})
+
+	_, err := Default.Create(Syslog, SyslogConfig{Network: "unix", Address: "/dev/log"})
+	assert.Error(t, err)
+}
+
+func TestWebhook(t *testing.T) {
+	const secret = "secret"
+	var verified []byte
+	var verifyErr error
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+			return
+		}
+		verified, verifyErr = VerifyWebhook(secret, r, time.Minute)
+		if verifyErr != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	notifier, err := Default.Create(Webhook, WebhookConfig{URL: server.URL, Secret: secret})
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(&Notification{Body: "signed", Severity: Warning}))
+	assert.NoError(t, verifyErr)
+	assert.Contains(t, string(verified), `"body":"signed"`)
+
+	// Неверный секрет и устаревшее время отвергаются.
+	notifier, err = Default.Create(Webhook, WebhookConfig{URL: server.URL, Secret: "wrong"})
+	assert.NoError(t, err)
+	assert.Error(t, notifier.Notify(Text("forged")))
+	assert.ErrorContains(t, verifyErr, "signature mismatch")
+
+	// Зависший получатель не задерживает отправку дольше ограничения времени.
+	notifier, err = Default.Create(Webhook, WebhookConfig{URL: server.URL + "/slow", Secret: secret, Timeout: Duration(50 * time.Millisecond)})
+	assert.NoError(t, err)
+	err = notifier.Notify(Text("stuck"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, Retryable(err))
+
+	body := []byte(`{}`)
+	timestamp := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+	_, err = VerifyWebhook(secret, r, time.Minute)
+	assert.ErrorContains(t, err, "out of tolerance")
+}
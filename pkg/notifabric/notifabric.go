@@ -1,46 +1,124 @@
 package notifabric
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"io"
+	"sync"
 )
 
 const (
-	File = "file"
-	Http = "http"
-	Log  = "log"
+	File    = "file"
+	Http    = "http"
+	Log     = "log"
+	SMTP    = "smtp"
+	Syslog  = "syslog"
+	Webhook = "webhook"
 )
 
-type Notifier interface{ Notify(message string) error }
+// Notifier есть уведомитель. Для строкового API предназначены адаптеры AsText и FromText.
+// Notify равносилен NotifyContext с context.Background.
+type Notifier interface {
+	Notify(n *Notification) error
+	NotifyContext(ctx context.Context, n *Notification) error
+}
 
 // Notifabric есть фабрика по созданию уведомителей.
 type Notifabric struct {
-	outputFile      string
-	httpDestination string
-	logTag          string
-	customWriters   map[string]io.Writer
+	registry  *Registry            // Реестр типов уведомителей.
+	configs   map[string]any       // Конфигурации уведомителей по типам.
+	templates map[string]*Template // Шаблоны представления уведомлений по типам уведомителей.
+
+	mu      sync.Mutex
+	closers []io.Closer // Созданные уведомители, удерживающие ресурсы.
+}
+
+// Option предназначен для настройки фабрики в конструкторе.
+type Option func(*Notifabric)
+
+// WithTemplate задаёт шаблон представления уведомлений для типа уведомителя, в том числе пользовательского.
+func WithTemplate(kind string, tmpl *Template) Option {
+	return func(n *Notifabric) {
+		if tmpl != nil {
+			n.templates[kind] = tmpl
+		}
+	}
+}
+
+// WithRegistry задаёт реестр типов уведомителей вместо Default.
+func WithRegistry(registry *Registry) Option {
+	return func(n *Notifabric) { n.registry = registry }
+}
+
+// WithConfig задаёт конфигурацию уведомителя зарегистрированного типа.
+func WithConfig(kind string, config any) Option {
+	return func(n *Notifabric) { n.configs[kind] = config }
 }
 
 // New создаёт фабрику уведомителей. По типу уведомителя будет выдана конкретная настроенная реализация.
 // Имеется возможность также передать пользовательские интерфейсы io.Writer как способы отправки уведомлений.
-func New(outputFile, httpDestination, ioStream string, custom map[string]io.Writer) *Notifabric {
-	return &Notifabric{outputFile, httpDestination, ioStream, custom}
+// По умолчанию HTTP-уведомитель отправляет уведомление в JSON, файл и io.Writer получают только текст сообщения,
+// как в строковом API; структурированное представление включается шаблоном DefaultText через WithTemplate.
+// Для новых типов уведомителей предпочтительны реестр и конфигурация: Register, Registry.Load.
+func New(outputFile, httpDestination, ioStream string, custom map[string]io.Writer, options ...Option) *Notifabric {
+	fabric := &Notifabric{
+		registry:  Default,
+		configs:   map[string]any{},
+		templates: map[string]*Template{},
+	}
+	for _, v := range options {
+		v(fabric)
+	}
+
+	fabric.registry = fabric.registry.clone()
+	fabric.setDefault(File, FileConfig{Path: outputFile, Template: fabric.templates[File]})
+	fabric.setDefault(Http, HTTPConfig{URL: httpDestination, Template: fabric.templates[Http]})
+	fabric.setDefault(Log, LogConfig{Tag: ioStream, Template: fabric.templates[Log]})
+	for kind, w := range custom {
+		if err := Register(fabric.registry, kind, newCustomNotifier); err == nil {
+			fabric.setDefault(kind, WriterConfig{Writer: w, Template: fabric.templates[kind]})
+		}
+	}
+	return fabric
 }
 
+// Kinds возвращает упорядоченные типы уведомителей, доступные фабрике.
+func (slf *Notifabric) Kinds() []string { return slf.registry.Kinds() }
+
+// CreateNotificator создаёт уведомитель заданного типа по конфигурации фабрики.
+// Уведомители, удерживающие ресурсы, закрываются вместе с фабрикой.
 func (slf *Notifabric) CreateNotificator(kind string) (Notifier, error) {
-	switch kind {
-	case File:
-		return &fileNotifier{slf.outputFile}, nil
-	case Http:
-		return &httpNotifier{slf.httpDestination}, nil
-	case Log:
-		return &logNotifier{slf.logTag}, nil
-	default:
-		if slf.customWriters != nil {
-			if w, ok := slf.customWriters[string(kind)]; ok {
-				return &customNotifier{w}, nil
-			}
+	notifier, err := slf.registry.Create(kind, slf.configs[kind])
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := notifier.(io.Closer); ok {
+		slf.mu.Lock()
+		slf.closers = append(slf.closers, closer)
+		slf.mu.Unlock()
+	}
+	return notifier, nil
+}
+
+// Close закрывает созданные фабрикой уведомители. Уведомители, уже закрытые вызывающим, пропускаются.
+func (slf *Notifabric) Close() error {
+	slf.mu.Lock()
+	closers := slf.closers
+	slf.closers = nil
+	slf.mu.Unlock()
+
+	var errs []error
+	for _, v := range closers {
+		if err := v.Close(); err != nil && !errors.Is(err, ErrClosed) {
+			errs = append(errs, err)
 		}
 	}
-	return nil, fmt.Errorf("unknown notification type")
+	return errors.Join(errs...)
+}
+
+// setDefault задаёт конфигурацию типа, если она не была задана через WithConfig.
+func (slf *Notifabric) setDefault(kind string, config any) {
+	if _, ok := slf.configs[kind]; !ok {
+		slf.configs[kind] = config
+	}
 }
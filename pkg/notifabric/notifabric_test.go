@@ -0,0 +1,173 @@
+package notifabric
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotification(t *testing.T) {
+	n := &Notification{
+		Title:     "disk",
+		Body:      "usage is 95%",
+		Severity:  Warning,
+		Labels:    map[string]string{"host": "db-1"},
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	b, err := MustTemplate(TextTemplate(DefaultText)).Render(n)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-02T03:04:05Z [warning] disk: usage is 95%", string(b))
+
+	b, err = MustTemplate(JSONTemplate("")).Render(n)
+	assert.NoError(t, err)
+	decoded := &Notification{}
+	assert.NoError(t, json.Unmarshal(b, decoded))
+	assert.Equal(t, n, decoded)
+
+	b, err = MustTemplate(JSONTemplate(`{"text":{{json .Body}},"host":{{json (index .Labels "host")}}}`)).Render(n)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"text":"usage is 95%","host":"db-1"}`, string(b))
+
+	_, err = MustTemplate(JSONTemplate(`{"text":{{.Body}}}`)).Render(n)
+	assert.Error(t, err)
+	_, err = TextTemplate("{{.Unclosed")
+	assert.Error(t, err)
+
+	severity := Severity(0)
+	assert.NoError(t, severity.UnmarshalText([]byte("CRITICAL")))
+	assert.Equal(t, Critical, severity)
+	assert.Error(t, severity.UnmarshalText([]byte("fatal")))
+}
+
+func TestNotifabric(t *testing.T) {
+	var contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "notifications")
+	custom, plain := &bytes.Buffer{}, &bytes.Buffer{}
+	fabric := New(
+		path, server.URL, "tag", map[string]io.Writer{"custom": custom, "plain": plain},
+		WithTemplate("custom", MustTemplate(TextTemplate(DefaultText))),
+	)
+	defer fabric.Close()
+
+	notifier, err := fabric.CreateNotificator(Http)
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(&Notification{Title: "title", Body: "body", Severity: Error}))
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, `{"title":"title","body":"body","severity":"error","timestamp":"0001-01-01T00:00:00Z"}`, body)
+
+	// Строковый API доступен через адаптер; по умолчанию в файл пишется только текст сообщения.
+	notifier, err = fabric.CreateNotificator(File)
+	assert.NoError(t, err)
+	assert.NoError(t, AsText(notifier).Notify("first"))
+	assert.NoError(t, AsText(notifier).Notify("second"))
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(b))
+
+	notifier, err = fabric.CreateNotificator("custom")
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(&Notification{Body: "body", Severity: Critical}))
+	assert.Contains(t, custom.String(), "[critical] body")
+	notifier, err = fabric.CreateNotificator("plain")
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(&Notification{Body: "body", Severity: Critical}))
+	assert.Equal(t, "body", plain.String())
+
+	_, err = fabric.CreateNotificator("unknown")
+	assert.Error(t, err)
+
+	// Уведомитель со строковым API принимает уведомления в текстовом представлении.
+	legacy := &testTextNotifier{}
+	assert.NoError(t, FromText(legacy, MustTemplate(TextTemplate("{{.Severity}}: {{.Body}}"))).Notify(Text("hello")))
+	assert.Equal(t, []string{"info: hello"}, legacy.messages)
+}
+
+func TestHTTP(t *testing.T) {
+	var header http.Header
+	status := http.StatusOK
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// Конфигурация с заголовками, токеном и ограничением времени читается из YAML.
+	notifiers, err := Default.Load([]byte(`
+notifiers:
+  api:
+    kind: http
+    config:
+      url: ` + server.URL + `
+      timeout: 5s
+      token: secret
+      headers:
+        X-Source: test
+`))
+	assert.NoError(t, err)
+	assert.NoError(t, notifiers["api"].Notify(Text("hello")))
+	assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+	assert.Equal(t, "test", header.Get("X-Source"))
+
+	notifier, err := Default.Create(Http, HTTPConfig{URL: server.URL, Username: "user", Password: "password"})
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(Text("hello")))
+	username, password, _ := (&http.Request{Header: header}).BasicAuth()
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "password", password)
+
+	_, err = Default.Create(Http, HTTPConfig{URL: server.URL, Token: "secret", Username: "user"})
+	assert.Error(t, err)
+
+	// Ошибки классифицируются по возможности повтора.
+	status = http.StatusServiceUnavailable
+	err = notifier.Notify(Text("hello"))
+	assert.Equal(t, &StatusError{http.StatusServiceUnavailable}, err)
+	assert.True(t, Retryable(err))
+	status = http.StatusNotFound
+	assert.False(t, Retryable(notifier.Notify(Text("hello"))))
+	status = http.StatusOK
+
+	notifier, err = Default.Create(Http, HTTPConfig{URL: server.URL + "/slow", Timeout: Duration(50 * time.Millisecond)})
+	assert.NoError(t, err)
+	err = notifier.Notify(Text("hello"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, Retryable(err))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, Retryable(notifier.NotifyContext(ctx, Text("hello"))))
+
+	_, err = MustTemplate(TextTemplate("{{.Missing}}")).Render(Text("hello"))
+	assert.False(t, Retryable(err))
+	assert.False(t, Retryable(Permanent(io.EOF)))
+	assert.True(t, Retryable(io.EOF))
+}
+
+type testTextNotifier struct{ messages []string }
+
+func (slf *testTextNotifier) Notify(message string) error {
+	slf.messages = append(slf.messages, message)
+	return nil
+}
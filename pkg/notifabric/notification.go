@@ -0,0 +1,203 @@
+package notifabric
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Severity есть уровень важности уведомления.
+type Severity int
+
+const (
+	Debug Severity = iota
+	Info
+	Warning
+	Error
+	Critical
+)
+
+var severities = [...]string{"debug", "info", "warning", "error", "critical"}
+
+func (slf Severity) String() string {
+	if slf < 0 || int(slf) >= len(severities) {
+		return fmt.Sprintf("severity(%d)", int(slf))
+	}
+	return severities[slf]
+}
+
+// MarshalText представляет уровень важности его названием, в том числе в JSON и YAML.
+func (slf Severity) MarshalText() ([]byte, error) { return []byte(slf.String()), nil }
+
+// UnmarshalText разбирает уровень важности по названию без учёта регистра.
+func (slf *Severity) UnmarshalText(text []byte) error {
+	for i, v := range severities {
+		if strings.EqualFold(v, string(text)) {
+			*slf = Severity(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// Notification есть уведомление.
+type Notification struct {
+	Title     string            `json:"title,omitempty"`
+	Body      string            `json:"body"`
+	Severity  Severity          `json:"severity"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Text создаёт уведомление уровня Info из строки сообщения.
+func Text(message string) *Notification {
+	return &Notification{Body: message, Severity: Info, Timestamp: time.Now().UTC()}
+}
+
+const (
+	// DefaultText есть шаблон структурированного текстового представления уведомления: время, уровень важности,
+	// заголовок и текст. Используется по умолчанию в письмах и FromText; для файла и io.Writer задаётся явно.
+	DefaultText = `{{.Timestamp.Format "2006-01-02T15:04:05Z07:00"}} [{{.Severity}}]{{with .Title}} {{.}}:{{end}} {{.Body}}`
+	// MessageText есть шаблон, оставляющий только текст сообщения, как в строковом API.
+	// Используется по умолчанию для файла и io.Writer.
+	MessageText = `{{.Body}}`
+)
+
+// Template преобразует уведомление в текст или JSON для отправки.
+// В конфигурации шаблон задаётся строкой текстового шаблона либо объектом {"format": "text" | "json", "text": "..."}.
+type Template struct {
+	ContentType string // MIME-тип результата.
+	tmpl        *template.Template
+}
+
+// UnmarshalJSON создаёт шаблон из конфигурации.
+func (slf *Template) UnmarshalJSON(data []byte) error {
+	definition := struct {
+		Format string `json:"format"`
+		Text   string `json:"text"`
+	}{Format: "text"}
+	if err := json.Unmarshal(data, &definition.Text); err != nil {
+		if err := json.Unmarshal(data, &definition); err != nil {
+			return err
+		}
+	}
+
+	var tmpl *Template
+	var err error
+	switch definition.Format {
+	case "text":
+		tmpl, err = TextTemplate(definition.Text)
+	case "json":
+		tmpl, err = JSONTemplate(definition.Text)
+	default:
+		err = fmt.Errorf("unknown template format %q", definition.Format)
+	}
+	if err != nil {
+		return err
+	}
+	*slf = *tmpl
+	return nil
+}
+
+// TextTemplate создаёт шаблон текстового представления на основе text/template.
+// В шаблоне доступны поля Notification и функция json для вставки значений в формате JSON.
+func TextTemplate(text string) (*Template, error) {
+	tmpl, err := parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{ContentType: "text/plain; charset=utf-8", tmpl: tmpl}, nil
+}
+
+// JSONTemplate создаёт шаблон представления в формате JSON на основе text/template.
+// Пустой шаблон представляет уведомление целиком. Результат проверяется на корректность JSON.
+func JSONTemplate(text string) (*Template, error) {
+	if text == "" {
+		return &Template{ContentType: "application/json"}, nil
+	}
+	tmpl, err := parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{ContentType: "application/json", tmpl: tmpl}, nil
+}
+
+// MustTemplate возвращает шаблон или паникует при ошибке его создания.
+// Предназначен для шаблонов, заданных в коде.
+func MustTemplate(tmpl *Template, err error) *Template {
+	if err != nil {
+		panic(err)
+	}
+	return tmpl
+}
+
+// Render преобразует уведомление согласно шаблону. Ошибки преобразования постоянны.
+func (slf *Template) Render(n *Notification) ([]byte, error) {
+	if slf.tmpl == nil {
+		b, err := json.Marshal(n)
+		return b, Permanent(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := slf.tmpl.Execute(buf, n); err != nil {
+		return nil, Permanent(err)
+	}
+	if slf.ContentType == "application/json" && !json.Valid(buf.Bytes()) {
+		return nil, Permanent(fmt.Errorf("template <%v> rendered invalid JSON", slf.tmpl.Name()))
+	}
+	return buf.Bytes(), nil
+}
+
+func parse(text string) (*template.Template, error) {
+	return template.New("notification").
+		Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).
+		Parse(text)
+}
+
+// TextNotifier есть уведомитель со строковым API.
+type TextNotifier interface{ Notify(message string) error }
+
+// AsText приспосабливает уведомитель к строковому API: сообщение отправляется как уведомление уровня Info.
+func AsText(notifier Notifier) TextNotifier { return &textAdapter{notifier} }
+
+// FromText приспосабливает уведомитель со строковым API: уведомление передаётся ему в текстовом представлении.
+// При отсутствии шаблона используется DefaultText.
+func FromText(notifier TextNotifier, tmpl *Template) Notifier {
+	if tmpl == nil {
+		tmpl = MustTemplate(TextTemplate(DefaultText))
+	}
+	return &fromTextAdapter{notifier, tmpl}
+}
+
+type textAdapter struct{ notifier Notifier }
+
+func (slf *textAdapter) Notify(message string) error { return slf.notifier.Notify(Text(message)) }
+
+type fromTextAdapter struct {
+	notifier TextNotifier
+	tmpl     *Template
+}
+
+func (slf *fromTextAdapter) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+// NotifyContext проверяет контекст только перед отправкой: строковый API не поддерживает отмену.
+func (slf *fromTextAdapter) NotifyContext(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b, err := slf.tmpl.Render(n)
+	if err != nil {
+		return err
+	}
+	return slf.notifier.Notify(string(b))
+}
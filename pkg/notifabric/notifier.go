@@ -2,48 +2,271 @@ package notifabric
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type httpNotifier struct{ url string }
+// HTTPConfig есть конфигурация HTTP-уведомителя. По умолчанию уведомление отправляется в JSON.
+// Аутентификация задаётся либо токеном (Bearer), либо именем пользователя и паролем (Basic).
+type HTTPConfig struct {
+	URL      string            `json:"url"`
+	Template *Template         `json:"template,omitempty"`
+	Timeout  Duration          `json:"timeout,omitempty"` // Ограничение времени запроса; по умолчанию 10 секунд.
+	Headers  map[string]string `json:"headers,omitempty"`
+	Token    string            `json:"token,omitempty"`
+	Username string            `json:"username,omitempty"`
+	Password string            `json:"password,omitempty"`
+	Client   *http.Client      `json:"-"` // Клиент для переиспользования соединений; по умолчанию создаётся свой.
+}
+
+func newHTTPNotifier(config HTTPConfig) (Notifier, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("empty url")
+	}
+	if config.Token != "" && config.Username != "" {
+		return nil, fmt.Errorf("token and basic auth are mutually exclusive")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = Duration(10 * time.Second)
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{}
+	}
+	return &httpNotifier{config, orDefault(config.Template, MustTemplate(JSONTemplate("")))}, nil
+}
+
+type httpNotifier struct {
+	config HTTPConfig
+	tmpl   *Template
+}
+
+func (slf *httpNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *httpNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	b, err := slf.tmpl.Render(n)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(slf.config.Timeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slf.config.URL, bytes.NewReader(b))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", slf.tmpl.ContentType)
+	for k, v := range slf.config.Headers {
+		req.Header.Set(k, v)
+	}
+	switch {
+	case slf.config.Token != "":
+		req.Header.Set("Authorization", "Bearer "+slf.config.Token)
+	case slf.config.Username != "":
+		req.SetBasicAuth(slf.config.Username, slf.config.Password)
+	}
+	return send(slf.config.Client, req)
+}
 
-func (slf *httpNotifier) Notify(message string) error {
-	res, err := http.Post(slf.url, "applicaton/json", bytes.NewBufferString(message))
+// send исполняет запрос и возвращает *StatusError при коде ответа вне 2xx.
+// Тело ответа вычитывается и закрывается, чтобы соединение могло быть переиспользовано.
+func send(client *http.Client, req *http.Request) error {
+	res, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<20))
 	if code := res.StatusCode; code > 299 {
-		return fmt.Errorf("unexpected status code %v", code)
+		return &StatusError{code}
 	}
 	return nil
 }
 
-type fileNotifier struct{ path string }
+// FileConfig есть конфигурация уведомителя, дописывающего уведомления в файл построчно.
+// Файл держится открытым и ротируется согласно Rotation. Уведомители с одним путём пишут в общий файл,
+// который закрывается вместе с последним из них.
+type FileConfig struct {
+	Path     string    `json:"path"`
+	Template *Template `json:"template,omitempty"`
+	Rotation
+}
+
+func newFileNotifier(config FileConfig) (Notifier, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	path, err := filepath.Abs(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := files.acquire(path, config.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	return &fileNotifier{f: f, path: path, tmpl: orDefault(config.Template, MustTemplate(TextTemplate(MessageText)))}, nil
+}
+
+type fileNotifier struct {
+	f      *RotatingFile
+	path   string // Абсолютный путь, по которому файл учтён в files.
+	tmpl   *Template
+	closed atomic.Bool
+}
 
-func (slf *fileNotifier) Notify(message string) error {
-	f, err := os.OpenFile(slf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm)
+func (slf *fileNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *fileNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if slf.closed.Load() {
+		return ErrClosed
+	}
+	b, err := slf.tmpl.Render(n)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	_, err = f.WriteString(message + "\n")
+	_, err = slf.f.Write(append(b, '\n'))
 	return err
 }
 
-type logNotifier struct{ tag string }
+// Close отказывается от файла уведомлений; файл закрывается, когда от него откажутся все уведомители.
+func (slf *fileNotifier) Close() error {
+	if !slf.closed.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
+	return files.release(slf.path)
+}
+
+// files есть файлы уведомлений, открытые в процессе: по одному на путь, чтобы ротация одного
+// не портила запись другого.
+var files = &fileSet{files: map[string]*sharedFile{}}
+
+type fileSet struct {
+	mu    sync.Mutex
+	files map[string]*sharedFile // Файлы по абсолютному пути.
+}
 
-func (slf *logNotifier) Notify(message string) error {
-	log.Printf("[%v] %v\n", slf.tag, message)
+type sharedFile struct {
+	f    *RotatingFile
+	refs int // Число уведомителей, пишущих в файл.
+}
+
+// acquire возвращает открытый по пути файл, открывая его при первом обращении.
+// Файл, уже открытый с другой ротацией, не выдаётся.
+func (slf *fileSet) acquire(path string, rotation Rotation) (*RotatingFile, error) {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if v, ok := slf.files[path]; ok {
+		if v.f.rotation != rotation {
+			return nil, fmt.Errorf("file <%v> is already open with another rotation", path)
+		}
+		v.refs++
+		return v.f, nil
+	}
+	f, err := OpenRotating(path, rotation)
+	if err != nil {
+		return nil, err
+	}
+	slf.files[path] = &sharedFile{f, 1}
+	return f, nil
+}
+
+// release закрывает файл, когда от него отказался последний уведомитель.
+func (slf *fileSet) release(path string) error {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	v, ok := slf.files[path]
+	if !ok {
+		return ErrClosed
+	}
+	if v.refs--; v.refs > 0 {
+		return nil
+	}
+	delete(slf.files, path)
+	return v.f.Close()
+}
+
+// LogConfig есть конфигурация уведомителя, выводящего уведомления в стандартный журнал с тегом.
+type LogConfig struct {
+	Tag      string    `json:"tag"`
+	Template *Template `json:"template,omitempty"`
+}
+
+func newLogNotifier(config LogConfig) (Notifier, error) {
+	tmpl := MustTemplate(TextTemplate(`[{{.Severity}}]{{with .Title}} {{.}}:{{end}} {{.Body}}`))
+	return &logNotifier{config.Tag, orDefault(config.Template, tmpl)}, nil
+}
+
+type logNotifier struct {
+	tag  string
+	tmpl *Template
+}
+
+func (slf *logNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *logNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b, err := slf.tmpl.Render(n)
+	if err != nil {
+		return err
+	}
+	log.Printf("[%v] %s\n", slf.tag, b)
 	return nil
 }
 
-type customNotifier struct{ w io.Writer }
+// WriterConfig есть конфигурация уведомителя, пишущего уведомления в io.Writer. Задаётся только в коде.
+type WriterConfig struct {
+	Writer   io.Writer `json:"-"`
+	Template *Template `json:"template,omitempty"`
+}
+
+func newCustomNotifier(config WriterConfig) (Notifier, error) {
+	if config.Writer == nil {
+		return nil, fmt.Errorf("empty writer")
+	}
+	return &customNotifier{config.Writer, orDefault(config.Template, MustTemplate(TextTemplate(MessageText)))}, nil
+}
+
+type customNotifier struct {
+	w    io.Writer
+	tmpl *Template
+}
+
+func (slf *customNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
 
-func (slf *customNotifier) Notify(message string) error {
-	_, err := slf.w.Write([]byte(message))
+func (slf *customNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b, err := slf.tmpl.Render(n)
+	if err != nil {
+		return err
+	}
+	_, err = slf.w.Write(b)
 	return err
 }
+
+func orDefault(tmpl, defaultTmpl *Template) *Template {
+	if tmpl != nil {
+		return tmpl
+	}
+	return defaultTmpl
+}
@@ -0,0 +1,165 @@
+package notifabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Constructor создаёт уведомитель по типизированной конфигурации.
+type Constructor[C any] func(config C) (Notifier, error)
+
+// Registry есть реестр типов уведомителей: тип сопоставлен конструктору со своей конфигурацией.
+type Registry struct {
+	mu           sync.RWMutex
+	constructors map[string]func(config any) (Notifier, error)
+}
+
+// Default есть реестр со встроенными типами уведомителей file, http, log, smtp, syslog и webhook.
+var Default = NewRegistry()
+
+func init() {
+	_ = Register(Default, File, newFileNotifier)
+	_ = Register(Default, Http, newHTTPNotifier)
+	_ = Register(Default, Log, newLogNotifier)
+	_ = Register(Default, SMTP, newSMTPNotifier)
+	_ = Register(Default, Syslog, newSyslogNotifier)
+	_ = Register(Default, Webhook, newWebhookNotifier)
+}
+
+// NewRegistry создаёт пустой реестр.
+func NewRegistry() *Registry {
+	return &Registry{constructors: map[string]func(config any) (Notifier, error){}}
+}
+
+// Register добавляет в реестр тип уведомителя. Повторная регистрация типа невозможна.
+// Конфигурация, переданная в виде json.RawMessage или прочитанная из файла, декодируется в C по тегам json.
+func Register[C any](r *Registry, kind string, constructor Constructor[C]) error {
+	if constructor == nil {
+		return fmt.Errorf("notifier kind <%v> has no constructor", kind)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.constructors[kind]; ok {
+		return fmt.Errorf("notifier kind <%v> is already registered", kind)
+	}
+	r.constructors[kind] = func(config any) (Notifier, error) {
+		var typed C
+		switch v := config.(type) {
+		case nil:
+		case C:
+			typed = v
+		case *C:
+			typed = *v
+		case json.RawMessage:
+			if err := json.Unmarshal(v, &typed); err != nil {
+				return nil, fmt.Errorf("notifier kind <%v> config: %w", kind, err)
+			}
+		default:
+			return nil, fmt.Errorf("notifier kind <%v> unexpected config type %T", kind, config)
+		}
+		return constructor(typed)
+	}
+	return nil
+}
+
+// Kinds возвращает упорядоченные зарегистрированные типы уведомителей.
+func (slf *Registry) Kinds() []string {
+	slf.mu.RLock()
+	defer slf.mu.RUnlock()
+	kinds := make([]string, 0, len(slf.constructors))
+	for kind := range slf.constructors {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// Create создаёт уведомитель заданного типа. Конфигурация передаётся значением или указателем
+// на тип конфигурации, либо в виде json.RawMessage; при её отсутствии используется нулевое значение.
+func (slf *Registry) Create(kind string, config any) (Notifier, error) {
+	slf.mu.RLock()
+	constructor, ok := slf.constructors[kind]
+	slf.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("notifier kind <%v> is not registered", kind)
+	}
+	return constructor(config)
+}
+
+// clone создаёт копию реестра.
+func (slf *Registry) clone() *Registry {
+	slf.mu.RLock()
+	defer slf.mu.RUnlock()
+	r := NewRegistry()
+	for kind, v := range slf.constructors {
+		r.constructors[kind] = v
+	}
+	return r
+}
+
+// Load создаёт набор именованных уведомителей по конфигурации в формате YAML или JSON:
+//
+//	notifiers:
+//	  ops:
+//	    kind: file
+//	    config:
+//	      path: /var/log/ops.log
+//	      template: "{{.Severity}}: {{.Body}}"
+//
+// При ошибке уже созданные уведомители, удерживающие ресурсы, закрываются.
+func (slf *Registry) Load(data []byte) (map[string]Notifier, error) {
+	definition := struct {
+		Notifiers map[string]struct {
+			Kind   string `yaml:"kind"`
+			Config any    `yaml:"config"`
+		} `yaml:"notifiers"`
+	}{}
+	if err := yaml.Unmarshal(data, &definition); err != nil {
+		return nil, err
+	}
+
+	notifiers := make(map[string]Notifier, len(definition.Notifiers))
+	for name, v := range definition.Notifiers {
+		var config any
+		if v.Config != nil {
+			raw, err := json.Marshal(v.Config)
+			if err != nil {
+				closeAll(notifiers)
+				return nil, fmt.Errorf("notifier <%v> config: %w", name, err)
+			}
+			config = json.RawMessage(raw)
+		}
+		notifier, err := slf.Create(v.Kind, config)
+		if err != nil {
+			closeAll(notifiers)
+			return nil, fmt.Errorf("notifier <%v>: %w", name, err)
+		}
+		notifiers[name] = notifier
+	}
+	return notifiers, nil
+}
+
+// closeAll закрывает уведомители, удерживающие ресурсы.
+func closeAll(notifiers map[string]Notifier) {
+	for _, v := range notifiers {
+		if closer, ok := v.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+}
+
+// LoadFile создаёт набор именованных уведомителей по файлу конфигурации.
+func (slf *Registry) LoadFile(path string) (map[string]Notifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return slf.Load(data)
+}
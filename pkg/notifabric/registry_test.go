@@ -0,0 +1,107 @@
+package notifabric
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConfig struct {
+	Prefix string   `json:"prefix"`
+	Min    Severity `json:"min"`
+}
+
+type testNotifier struct {
+	config testConfig
+	out    *bytes.Buffer
+}
+
+func (slf *testNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *testNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	if n.Severity >= slf.config.Min {
+		fmt.Fprintf(slf.out, "%v%v\n", slf.config.Prefix, n.Body)
+	}
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	out := &bytes.Buffer{}
+	registry := Default.clone()
+	assert.NoError(t, Register(registry, "test", func(config testConfig) (Notifier, error) {
+		return &testNotifier{config, out}, nil
+	}))
+	assert.Error(t, Register(registry, "test", func(testConfig) (Notifier, error) { return nil, nil }))
+	assert.Equal(t, []string{File, Http, Log, SMTP, Syslog, "test", Webhook}, registry.Kinds())
+	assert.Equal(t, []string{File, Http, Log, SMTP, Syslog, Webhook}, Default.Kinds())
+
+	// Набор уведомителей создаётся по конфигурации в YAML.
+	path := filepath.Join(t.TempDir(), "notifications")
+	notifiers, err := registry.Load([]byte(`
+notifiers:
+  alerts:
+    kind: test
+    config:
+      prefix: "alert: "
+      min: error
+  audit:
+    kind: file
+    config:
+      path: ` + path + `
+      template: "{{.Severity}} {{.Body}}"
+`))
+	assert.NoError(t, err)
+	assert.Len(t, notifiers, 2)
+	for _, v := range notifiers {
+		assert.NoError(t, v.Notify(&Notification{Body: "low", Severity: Info}))
+		assert.NoError(t, v.Notify(&Notification{Body: "high", Severity: Critical}))
+	}
+	assert.Equal(t, "alert: high\n", out.String())
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "info low\ncritical high\n", string(b))
+	closeAll(notifiers)
+
+	// Уведомители, созданные до ошибки, закрываются и не удерживают файл.
+	_, err = registry.Load([]byte(`
+notifiers:
+  audit: {kind: file, config: {path: ` + path + `}}
+  broken: {kind: unknown}
+`))
+	assert.Error(t, err)
+	files.mu.Lock()
+	assert.NotContains(t, files.files, path)
+	files.mu.Unlock()
+	notifiers, err = registry.Load([]byte(`notifiers: {audit: {kind: file, config: {path: ` + path + `, max_size: 1024}}}`))
+	assert.NoError(t, err)
+	closeAll(notifiers)
+
+	// Конфигурация в JSON и шаблон в виде объекта.
+	notifiers, err = registry.Load([]byte(`{"notifiers": {"hook": {"kind": "http", "config": {"url": "http://localhost", "template": {"format": "json", "text": "{\"text\": {{json .Body}}}"}}}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", notifiers["hook"].(*httpNotifier).tmpl.ContentType)
+
+	for _, config := range []string{
+		`notifiers: {x: {kind: unknown}}`,
+		`notifiers: {x: {kind: test, config: {min: fatal}}}`,
+		`notifiers: {x: {kind: file}}`,
+		`notifiers: {x: {kind: log, config: {template: {format: xml}}}}`,
+	} {
+		_, err := registry.Load([]byte(config))
+		assert.Error(t, err, config)
+	}
+
+	// Фабрика использует реестр и конфигурации по типам.
+	fabric := New("", "", "", nil, WithRegistry(registry), WithConfig("test", testConfig{Prefix: "> "}))
+	notifier, err := fabric.CreateNotificator("test")
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(Text("fabric")))
+	assert.Contains(t, out.String(), "> fabric\n")
+}
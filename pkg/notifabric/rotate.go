@@ -0,0 +1,268 @@
+package notifabric
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupLayout есть формат времени ротации в имени резервной копии: <файл>.<время>.gz.
+// Лексикографический порядок имён совпадает с порядком ротаций.
+const backupLayout = "20060102T150405.000000000"
+
+// Rotation есть настройка ротации и сброса на диск файла уведомлений.
+type Rotation struct {
+	MaxSize  int64    `json:"max_size,omitempty"` // Размер файла в байтах, по достижении которого он ротируется; 0 — без ограничения.
+	Interval Duration `json:"interval,omitempty"` // Время с открытия файла, по истечении которого он ротируется; 0 — без ограничения.
+	Backups  int      `json:"backups,omitempty"`  // Число хранимых сжатых копий; 0 — хранятся все.
+	Sync     Duration `json:"sync,omitempty"`     // Период сброса записанного на диск; 0 — только при ротации и закрытии.
+}
+
+// RotatingFile есть файл для дозаписи, который держится открытым и ротируется по размеру или времени.
+// Ротированный файл переименовывается и сжимается gzip в фоне, лишние старые копии удаляются.
+// Безопасен для конкурентного использования. Один файл не должен открываться несколькими экземплярами:
+// файловые уведомители поэтому разделяют один экземпляр на путь.
+type RotatingFile struct {
+	path     string
+	rotation Rotation
+	now      func() time.Time // Часы для отсчёта Interval.
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+	dirty  bool
+	closed bool
+
+	compress sync.Mutex     // Сжатие копий выполняется по одному.
+	wg       sync.WaitGroup // Фоновые сжатие и сброс на диск.
+	stop     chan struct{}
+}
+
+// OpenRotating открывает файл для дозаписи, создавая его при отсутствии.
+// Копии, оставшиеся несжатыми после предыдущего запуска, сжимаются в фоне.
+func OpenRotating(path string, rotation Rotation) (*RotatingFile, error) {
+	slf := &RotatingFile{path: path, rotation: rotation, now: time.Now, stop: make(chan struct{})}
+	if err := slf.open(); err != nil {
+		return nil, err
+	}
+	slf.wg.Add(1)
+	go slf.archive()
+	if rotation.Sync > 0 {
+		slf.wg.Add(1)
+		go slf.run()
+	}
+	return slf, nil
+}
+
+// Write дописывает данные в файл целиком. Перед записью файл ротируется, если запись превысит
+// MaxSize или истёк Interval. Запись, которая больше MaxSize сама по себе, попадает в пустой файл.
+func (slf *RotatingFile) Write(p []byte) (int, error) {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if slf.closed {
+		return 0, ErrClosed
+	}
+	if slf.size > 0 && slf.expired(int64(len(p))) {
+		if err := slf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := slf.f.Write(p)
+	slf.size += int64(n)
+	slf.dirty = slf.dirty || n > 0
+	return n, err
+}
+
+// Rotate немедленно ротирует файл.
+func (slf *RotatingFile) Rotate() error {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if slf.closed {
+		return ErrClosed
+	}
+	return slf.rotate()
+}
+
+// Sync сбрасывает записанное на диск.
+func (slf *RotatingFile) Sync() error {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if slf.closed {
+		return ErrClosed
+	}
+	return slf.sync()
+}
+
+// Close сбрасывает записанное на диск, закрывает файл и ожидает окончания сжатия копий.
+func (slf *RotatingFile) Close() error {
+	slf.mu.Lock()
+	if slf.closed {
+		slf.mu.Unlock()
+		return ErrClosed
+	}
+	slf.closed = true
+	close(slf.stop)
+	err := slf.sync()
+	if closeErr := slf.f.Close(); err == nil {
+		err = closeErr
+	}
+	slf.mu.Unlock()
+
+	slf.wg.Wait()
+	return err
+}
+
+func (slf *RotatingFile) open() error {
+	f, err := os.OpenFile(slf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	slf.f, slf.size, slf.opened = f, info.Size(), slf.now()
+	return nil
+}
+
+func (slf *RotatingFile) expired(size int64) bool {
+	if limit := slf.rotation.MaxSize; limit > 0 && slf.size+size > limit {
+		return true
+	}
+	interval := time.Duration(slf.rotation.Interval)
+	return interval > 0 && slf.now().Sub(slf.opened) >= interval
+}
+
+// rotate закрывает текущий файл, переименовывает его в несжатую копию и открывает новый.
+// Вызывается под блокировкой.
+func (slf *RotatingFile) rotate() error {
+	if err := slf.sync(); err != nil {
+		return err
+	}
+	if err := slf.f.Close(); err != nil {
+		return err
+	}
+	backup := slf.path + "." + time.Now().UTC().Format(backupLayout)
+	if err := os.Rename(slf.path, backup); err != nil {
+		// Файл уже закрыт: продолжаем запись в него же.
+		if openErr := slf.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := slf.open(); err != nil {
+		return err
+	}
+	slf.wg.Add(1)
+	go slf.archive()
+	return nil
+}
+
+func (slf *RotatingFile) sync() error {
+	if !slf.dirty {
+		return nil
+	}
+	slf.dirty = false
+	return slf.f.Sync()
+}
+
+// run периодически сбрасывает записанное на диск.
+func (slf *RotatingFile) run() {
+	defer slf.wg.Done()
+	ticker := time.NewTicker(time.Duration(slf.rotation.Sync))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-slf.stop:
+			return
+		case <-ticker.C:
+			slf.mu.Lock()
+			if !slf.closed {
+				_ = slf.sync()
+			}
+			slf.mu.Unlock()
+		}
+	}
+}
+
+// archive сжимает несжатые копии и удаляет старые сверх Backups. Ошибки не прерывают работу файла:
+// копия, которую не удалось сжать, будет сжата при следующей ротации.
+func (slf *RotatingFile) archive() {
+	defer slf.wg.Done()
+	slf.compress.Lock()
+	defer slf.compress.Unlock()
+
+	backups, err := slf.backups()
+	if err != nil {
+		return
+	}
+	for i, v := range backups {
+		if !strings.HasSuffix(v, ".gz") {
+			if err := compress(v); err == nil {
+				backups[i] = v + ".gz"
+			}
+		}
+	}
+	if n := slf.rotation.Backups; n > 0 && len(backups) > n {
+		for _, v := range backups[:len(backups)-n] {
+			_ = os.Remove(v)
+		}
+	}
+}
+
+// backups возвращает пути копий файла от старых к новым.
+func (slf *RotatingFile) backups() ([]string, error) {
+	dir, base := filepath.Split(slf.path)
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, v := range entries {
+		suffix, ok := strings.CutPrefix(v.Name(), base+".")
+		if !ok || v.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(backupLayout, strings.TrimSuffix(suffix, ".gz")); err == nil {
+			backups = append(backups, filepath.Join(dir, v.Name()))
+		}
+	}
+	slices.Sort(backups)
+	return backups, nil
+}
+
+// compress сжимает файл в <путь>.gz и удаляет исходный.
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if syncErr := dst.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
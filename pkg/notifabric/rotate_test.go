@@ -0,0 +1,157 @@
+package notifabric
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingFile(t *testing.T) {
+	// read возвращает строки копий от старых к новым и строки текущего файла.
+	read := func(t *testing.T, f *RotatingFile) (backups [][]string, current []string) {
+		paths, err := f.backups()
+		assert.NoError(t, err)
+		for _, v := range paths {
+			assert.True(t, strings.HasSuffix(v, ".gz"), v)
+			file, err := os.Open(v)
+			assert.NoError(t, err)
+			zr, err := gzip.NewReader(file)
+			assert.NoError(t, err)
+			b, err := io.ReadAll(zr)
+			assert.NoError(t, err)
+			file.Close()
+			backups = append(backups, strings.Fields(string(b)))
+		}
+		b, err := os.ReadFile(f.path)
+		assert.NoError(t, err)
+		return backups, strings.Fields(string(b))
+	}
+
+	t.Run("size", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notifications")
+		assert.NoError(t, os.WriteFile(path+".old", []byte("unrelated"), 0o644))
+		f, err := OpenRotating(path, Rotation{MaxSize: 8, Backups: 2})
+		assert.NoError(t, err)
+		for i := range 5 {
+			_, err := fmt.Fprintf(f, "line%v\n", i)
+			assert.NoError(t, err)
+		}
+		// Запись больше предела попадает в пустой файл целиком.
+		_, err = f.Write([]byte("oversized\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+		assert.ErrorIs(t, f.Close(), ErrClosed)
+		_, err = f.Write([]byte("late\n"))
+		assert.ErrorIs(t, err, ErrClosed)
+
+		backups, current := read(t, f)
+		assert.Equal(t, [][]string{{"line3"}, {"line4"}}, backups)
+		assert.Equal(t, []string{"oversized"}, current)
+		_, err = os.Stat(path + ".old")
+		assert.NoError(t, err)
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notifications")
+		f, err := OpenRotating(path, Rotation{Interval: Duration(time.Hour), Sync: Duration(time.Millisecond)})
+		assert.NoError(t, err)
+		now := time.Now()
+		f.now = func() time.Time { return now }
+		_, err = f.Write([]byte("first\n"))
+		assert.NoError(t, err)
+		now = now.Add(time.Hour)
+		_, err = f.Write([]byte("second\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+
+		backups, current := read(t, f)
+		assert.Equal(t, [][]string{{"first"}}, backups)
+		assert.Equal(t, []string{"second"}, current)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notifications")
+		f, err := OpenRotating(path, Rotation{MaxSize: 256})
+		assert.NoError(t, err)
+		wg := sync.WaitGroup{}
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range 100 {
+					_, err := fmt.Fprintf(f, "%v-%v\n", i, j)
+					assert.NoError(t, err)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.NoError(t, f.Close())
+
+		// Все строки сохранены целиком, ни одна не разорвана ротацией.
+		backups, current := read(t, f)
+		lines := map[string]bool{}
+		for _, v := range append(backups, current) {
+			for _, line := range v {
+				lines[line] = true
+			}
+		}
+		assert.Len(t, lines, 800)
+		assert.True(t, lines["7-99"])
+	})
+
+	t.Run("notifier", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notifications")
+		notifiers, err := Default.Load([]byte(`
+notifiers:
+  audit:
+    kind: file
+    config:
+      path: ` + path + `
+      template: "{{.Body}}"
+      max_size: 16
+      backups: 1
+      sync: 1s
+`))
+		assert.NoError(t, err)
+		for _, v := range []string{"alpha", "beta", "gamma", "delta"} {
+			assert.NoError(t, notifiers["audit"].Notify(Text(v)))
+		}
+		assert.NoError(t, notifiers["audit"].(io.Closer).Close())
+
+		backups, current := read(t, notifiers["audit"].(*fileNotifier).f)
+		assert.Equal(t, [][]string{{"alpha", "beta"}}, backups)
+		assert.Equal(t, []string{"gamma", "delta"}, current)
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notifications")
+		fabric := New(path, "", "", nil)
+		first, err := fabric.CreateNotificator(File)
+		assert.NoError(t, err)
+		second, err := fabric.CreateNotificator(File)
+		assert.NoError(t, err)
+		_, err = Default.Create(File, FileConfig{Path: path, Rotation: Rotation{MaxSize: 1}})
+		assert.ErrorContains(t, err, "another rotation")
+
+		// Уведомители с одним путём пишут в один файл, который закрывается последним из них.
+		f := first.(*fileNotifier).f
+		assert.Same(t, f, second.(*fileNotifier).f)
+		assert.NoError(t, first.(io.Closer).Close())
+		assert.ErrorIs(t, first.Notify(Text("late")), ErrClosed)
+		assert.NoError(t, second.Notify(Text("second")))
+		assert.NoError(t, fabric.Close())
+		assert.ErrorIs(t, second.Notify(Text("late")), ErrClosed)
+		assert.ErrorIs(t, f.Sync(), ErrClosed)
+
+		_, current := read(t, f)
+		assert.Equal(t, []string{"second"}, current)
+	})
+}
@@ -0,0 +1,144 @@
+package notifabric
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig есть конфигурация уведомителя, отправляющего уведомления по электронной почте.
+// Аутентификация PLAIN используется при заданном имени пользователя; она допускается только поверх TLS
+// либо при подключении к localhost.
+type SMTPConfig struct {
+	Address  string    `json:"address"` // Адрес сервера в виде host:port.
+	From     string    `json:"from"`
+	To       []string  `json:"to"`
+	Username string    `json:"username,omitempty"`
+	Password string    `json:"password,omitempty"`
+	Subject  *Template `json:"subject,omitempty"`  // Шаблон темы письма; по умолчанию уровень важности и заголовок.
+	Template *Template `json:"template,omitempty"` // Шаблон текста письма.
+	Timeout  Duration  `json:"timeout,omitempty"`  // Ограничение времени отправки письма; по умолчанию 10 секунд.
+}
+
+func newSMTPNotifier(config SMTPConfig) (Notifier, error) {
+	if config.Address == "" || config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("address, sender and recipients are required")
+	}
+	host, _, err := net.SplitHostPort(config.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = Duration(10 * time.Second)
+	}
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+	return &smtpNotifier{
+		config:  config,
+		auth:    auth,
+		subject: orDefault(config.Subject, MustTemplate(TextTemplate(`[{{.Severity}}]{{with .Title}} {{.}}{{end}}`))),
+		tmpl:    orDefault(config.Template, MustTemplate(TextTemplate(DefaultText))),
+	}, nil
+}
+
+type smtpNotifier struct {
+	config  SMTPConfig
+	auth    smtp.Auth
+	subject *Template
+	tmpl    *Template
+}
+
+func (slf *smtpNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *smtpNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	subject, err := slf.subject.Render(n)
+	if err != nil {
+		return err
+	}
+	body, err := slf.tmpl.Render(n)
+	if err != nil {
+		return err
+	}
+
+	timestamp := n.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %v\r\n", slf.config.From)
+	fmt.Fprintf(msg, "To: %v\r\n", strings.Join(slf.config.To, ", "))
+	fmt.Fprintf(msg, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", string(subject)))
+	fmt.Fprintf(msg, "Date: %v\r\n", timestamp.Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: %v\r\n\r\n", slf.tmpl.ContentType)
+	msg.Write(bytes.ReplaceAll(body, []byte("\n"), []byte("\r\n")))
+	msg.WriteString("\r\n")
+
+	return slf.send(ctx, msg.Bytes())
+}
+
+// send отправляет письмо так же, как smtp.SendMail, но с учётом контекста и Timeout:
+// соединение устанавливается через DialContext, а срок контекста становится сроком соединения.
+func (slf *smtpNotifier) send(ctx context.Context, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(slf.config.Timeout))
+	defer cancel()
+	host, _, _ := net.SplitHostPort(slf.config.Address)
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", slf.config.Address)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if slf.auth != nil {
+		if err := c.Auth(slf.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(slf.config.From); err != nil {
+		return err
+	}
+	for _, v := range slf.config.To {
+		if err := c.Rcpt(v); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,184 @@
+package notifabric
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogEnterprise есть номер предприятия в идентификаторе структурированных данных с метками уведомления.
+const syslogEnterprise = 32473
+
+// SyslogConfig есть конфигурация уведомителя, отправляющего уведомления в syslog в формате RFC 5424.
+// Поверх TCP сообщения разделяются префиксом длины согласно RFC 6587.
+type SyslogConfig struct {
+	Network  string    `json:"network"` // udp (по умолчанию) или tcp.
+	Address  string    `json:"address"`
+	Hostname string    `json:"hostname,omitempty"` // По умолчанию имя хоста.
+	AppName  string    `json:"app_name,omitempty"`
+	Facility int       `json:"facility,omitempty"` // По умолчанию user (1).
+	Template *Template `json:"template,omitempty"` // Шаблон текста сообщения.
+}
+
+func newSyslogNotifier(config SyslogConfig) (Notifier, error) {
+	if config.Network == "" {
+		config.Network = "udp"
+	}
+	if config.Network != "udp" && config.Network != "tcp" {
+		return nil, fmt.Errorf("unsupported network %q", config.Network)
+	}
+	if config.Address == "" {
+		return nil, fmt.Errorf("empty address")
+	}
+	if config.Facility == 0 {
+		config.Facility = 1
+	}
+	if config.Facility < 0 || config.Facility > 23 {
+		return nil, fmt.Errorf("facility %v is out of range", config.Facility)
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	return &syslogNotifier{config: config, tmpl: orDefault(config.Template, MustTemplate(TextTemplate(`{{with .Title}}{{.}}: {{end}}{{.Body}}`)))}, nil
+}
+
+type syslogNotifier struct {
+	config SyslogConfig
+	tmpl   *Template
+	mu     sync.Mutex
+	conn   net.Conn // Соединение устанавливается при первой отправке и восстанавливается после ошибки.
+	closed bool
+}
+
+func (slf *syslogNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *syslogNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	msg, err := slf.tmpl.Render(n)
+	if err != nil {
+		return err
+	}
+	line := slf.format(n, msg)
+	if slf.config.Network == "tcp" {
+		line = fmt.Sprintf("%d %s", len(line), line)
+	}
+
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if slf.closed {
+		return ErrClosed
+	}
+	if slf.conn == nil {
+		if slf.conn, err = (&net.Dialer{}).DialContext(ctx, slf.config.Network, slf.config.Address); err != nil {
+			return err
+		}
+	}
+	deadline, _ := ctx.Deadline()
+	if err := slf.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	if _, err := slf.conn.Write([]byte(line)); err != nil {
+		slf.conn.Close()
+		slf.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Close закрывает соединение с syslog; после закрытия уведомления не отправляются.
+func (slf *syslogNotifier) Close() error {
+	slf.mu.Lock()
+	defer slf.mu.Unlock()
+	if slf.closed {
+		return ErrClosed
+	}
+	slf.closed = true
+	if slf.conn == nil {
+		return nil
+	}
+	err := slf.conn.Close()
+	slf.conn = nil
+	return err
+}
+
+// format формирует сообщение RFC 5424: PRI VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG.
+func (slf *syslogNotifier) format(n *Notification, msg []byte) string {
+	timestamp := n.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return fmt.Sprintf(
+		"<%d>1 %v %v %v %d - %v %s",
+		slf.config.Facility*8+syslogSeverity(n.Severity),
+		timestamp.UTC().Format(time.RFC3339Nano),
+		nilValue(slf.config.Hostname),
+		nilValue(slf.config.AppName),
+		os.Getpid(),
+		structuredData(n.Labels),
+		msg,
+	)
+}
+
+// syslogSeverity сопоставляет уровень важности уведомления уровню syslog.
+func syslogSeverity(severity Severity) int {
+	switch {
+	case severity <= Debug:
+		return 7
+	case severity == Info:
+		return 6
+	case severity == Warning:
+		return 4
+	case severity == Error:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// structuredData представляет метки уведомления элементом структурированных данных.
+func structuredData(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	sd := &strings.Builder{}
+	fmt.Fprintf(sd, "[labels@%d", syslogEnterprise)
+	for _, k := range keys {
+		fmt.Fprintf(sd, ` %v="%v"`, sdName(k), escaper.Replace(labels[k]))
+	}
+	sd.WriteString("]")
+	return sd.String()
+}
+
+// sdName приводит имя параметра к допустимому: печатные ASCII-символы, кроме '=', ' ', ']' и '"', не длиннее 32.
+func sdName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+func nilValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,226 @@
+package notifabric
+
+import (
+	"context"
+	"fmt"
+	"licklib/pkg/ratelimit"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThrottleOption предназначен для настройки подавления уведомлений в конструкторе.
+type ThrottleOption func(*Throttler)
+
+// WithKey задаёт ключ, по которому уведомления считаются похожими.
+// По умолчанию ключом являются уровень важности, заголовок и текст уведомления.
+func WithKey(key func(n *Notification) string) ThrottleOption {
+	return func(t *Throttler) { t.key = key }
+}
+
+// WithDedupe подавляет повторы уведомления с тем же ключом в течение окна после его отправки.
+func WithDedupe(window time.Duration) ThrottleOption {
+	return func(t *Throttler) { t.dedupe = window }
+}
+
+// WithRateLimit ограничивает число отправляемых уведомлений с одним ключом в скользящем окне.
+func WithRateLimit(limit int64, window time.Duration) ThrottleOption {
+	return func(t *Throttler) { t.limit, t.limitWindow = limit, window }
+}
+
+// WithDigest задаёт период отправки сводки о подавленных уведомлениях.
+// Без периода сводка отправляется только через Flush и Close. Периодическая отправка прерывается закрытием уведомителя.
+func WithDigest(interval time.Duration) ThrottleOption {
+	return func(t *Throttler) { t.interval = interval }
+}
+
+// throttled есть состояние уведомлений с одним ключом.
+type throttled struct {
+	sent       time.Time              // Время последней отправки.
+	limiter    *ratelimit.TimeLimiter // Ограничитель отправки; может отсутствовать.
+	suppressed int                    // Число подавленных уведомлений с момента последней сводки.
+	sample     *Notification          // Первое подавленное уведомление.
+	severity   Severity               // Наибольший уровень важности подавленных уведомлений.
+}
+
+// Throttler есть уведомитель, подавляющий повторы и всплески уведомлений.
+// Подавленные уведомления не теряются бесследно: о них отправляется сводка.
+type Throttler struct {
+	target      Notifier
+	key         func(n *Notification) string
+	dedupe      time.Duration // Окно подавления повторов.
+	limit       int64         // Предельное число уведомлений с одним ключом в окне.
+	limitWindow time.Duration // Окно ограничения числа уведомлений.
+	interval    time.Duration // Период отправки сводки.
+
+	mu     sync.Mutex
+	keys   map[string]*throttled // Состояние по ключам.
+	since  time.Time             // Начало периода текущей сводки.
+	swept  time.Time             // Время последней очистки устаревших ключей.
+	closed bool                  // Флаг того, что новые уведомления не принимаются.
+	ctx    context.Context       // Контекст периодической отправки сводки; отменяется при закрытии.
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Throttle создаёт уведомитель, подавляющий повторы и всплески уведомлений перед отправкой получателю.
+func Throttle(target Notifier, options ...ThrottleOption) *Throttler {
+	now := time.Now()
+	throttler := &Throttler{
+		target: target,
+		key: func(n *Notification) string {
+			return fmt.Sprint(n.Severity, "\x00", n.Title, "\x00", n.Body)
+		},
+		keys:  map[string]*throttled{},
+		since: now,
+		swept: now,
+		done:  make(chan struct{}),
+	}
+	throttler.ctx, throttler.cancel = context.WithCancel(context.Background())
+	for _, v := range options {
+		v(throttler)
+	}
+
+	if throttler.interval > 0 {
+		go throttler.run()
+	} else {
+		close(throttler.done)
+	}
+	return throttler
+}
+
+// Notify отправляет уведомление, если оно не подавлено как повтор или превышение предела.
+// После закрытия возвращает ErrClosed.
+func (slf *Throttler) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *Throttler) NotifyContext(ctx context.Context, n *Notification) error {
+	key := slf.key(n)
+	now := time.Now()
+
+	slf.mu.Lock()
+	if slf.closed {
+		slf.mu.Unlock()
+		return ErrClosed
+	}
+	slf.sweep(now)
+	state, ok := slf.keys[key]
+	if !ok {
+		state = &throttled{}
+		if slf.limit > 0 {
+			state.limiter = ratelimit.NewTimeLimiter(slf.limit, slf.limitWindow)
+		}
+		slf.keys[key] = state
+	}
+	duplicate := slf.dedupe > 0 && !state.sent.IsZero() && now.Sub(state.sent) < slf.dedupe
+	if duplicate || (state.limiter != nil && !state.limiter.Allow()) {
+		state.suppressed++
+		if state.sample == nil {
+			state.sample = n
+		}
+		state.severity = max(state.severity, n.Severity)
+		slf.mu.Unlock()
+		return nil
+	}
+	// Отправка отмечается заранее, чтобы повторы во время неё подавлялись.
+	sent := state.sent
+	state.sent = now
+	slf.mu.Unlock()
+
+	err := slf.target.NotifyContext(ctx, n)
+	if err != nil {
+		// Неотправленное уведомление не должно подавлять свой повтор.
+		slf.mu.Lock()
+		if state.sent.Equal(now) {
+			state.sent = sent
+		}
+		slf.mu.Unlock()
+	}
+	return err
+}
+
+// Flush немедленно отправляет сводку о подавленных уведомлениях, если они были.
+func (slf *Throttler) Flush(ctx context.Context) error {
+	slf.mu.Lock()
+	digest := slf.digest(time.Now())
+	slf.mu.Unlock()
+
+	if digest == nil {
+		return nil
+	}
+	return slf.target.NotifyContext(ctx, digest)
+}
+
+// Close прекращает приём уведомлений, останавливает периодическую отправку сводки и отправляет итоговую сводку.
+// Повторный вызов возвращает ErrClosed.
+func (slf *Throttler) Close() error {
+	slf.mu.Lock()
+	if slf.closed {
+		slf.mu.Unlock()
+		return ErrClosed
+	}
+	slf.closed = true
+	slf.mu.Unlock()
+
+	slf.cancel()
+	<-slf.done
+	return slf.Flush(context.Background())
+}
+
+func (slf *Throttler) run() {
+	defer close(slf.done)
+	ticker := time.NewTicker(slf.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-slf.ctx.Done():
+			return
+		case <-ticker.C:
+			_ = slf.Flush(slf.ctx)
+		}
+	}
+}
+
+// digest формирует сводку о подавленных уведомлениях и начинает новый период.
+// Уровень важности сводки есть наибольший уровень подавленных уведомлений.
+func (slf *Throttler) digest(now time.Time) *Notification {
+	var lines []string
+	severity := Debug
+	for _, v := range slf.keys {
+		if v.suppressed == 0 {
+			continue
+		}
+		text := v.sample.Body
+		if v.sample.Title != "" {
+			text = v.sample.Title + ": " + text
+		}
+		lines = append(lines, fmt.Sprintf(
+			"%v similar notifications in the last %v: %v", v.suppressed, now.Sub(slf.since).Round(time.Second), text,
+		))
+		severity = max(severity, v.severity)
+		v.suppressed, v.sample, v.severity = 0, nil, Debug
+	}
+	slf.since = now
+	if len(lines) == 0 {
+		return nil
+	}
+
+	slices.Sort(lines)
+	return &Notification{Title: "digest", Body: strings.Join(lines, "\n"), Severity: severity, Timestamp: now.UTC()}
+}
+
+// sweep удаляет состояние ключей, по которым нет подавленных уведомлений и которые вышли за пределы окон.
+func (slf *Throttler) sweep(now time.Time) {
+	window := max(slf.dedupe, slf.limitWindow)
+	if now.Sub(slf.swept) < window {
+		return
+	}
+	for key, v := range slf.keys {
+		if v.suppressed == 0 && now.Sub(v.sent) >= window {
+			delete(slf.keys, key)
+		}
+	}
+	slf.swept = now
+}
@@ -0,0 +1,95 @@
+package notifabric
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	t.Run("dedupe", func(t *testing.T) {
+		target := &testRecorder{}
+		throttler := Throttle(target, WithDedupe(50*time.Millisecond))
+		for range 5 {
+			assert.NoError(t, throttler.Notify(&Notification{Title: "disk", Body: "full", Severity: Warning}))
+		}
+		assert.NoError(t, throttler.Notify(&Notification{Title: "disk", Body: "full", Severity: Error}))
+		assert.NoError(t, throttler.Notify(Text("other")))
+		assert.Equal(t, []string{"full", "full", "other"}, target.bodies)
+
+		// По истечении окна повтор снова отправляется.
+		time.Sleep(60 * time.Millisecond)
+		assert.NoError(t, throttler.Notify(&Notification{Title: "disk", Body: "full", Severity: Warning}))
+		assert.Len(t, target.bodies, 4)
+
+		assert.NoError(t, throttler.Close())
+		assert.Len(t, target.bodies, 5)
+		assert.Regexp(t, `^4 similar notifications in the last \S+: disk: full$`, target.bodies[4])
+
+		// Закрытый уведомитель отказывает в отправке, а не подавляет её молча.
+		assert.ErrorIs(t, throttler.Notify(Text("late")), ErrClosed)
+		assert.ErrorIs(t, throttler.Close(), ErrClosed)
+		assert.Len(t, target.bodies, 5)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		target := &testRecorder{}
+		throttler := Throttle(target, WithRateLimit(2, time.Hour), WithKey(func(n *Notification) string { return n.Labels["host"] }))
+		for i := range 10 {
+			host := fmt.Sprint("host", i%2)
+			assert.NoError(t, throttler.Notify(&Notification{Body: fmt.Sprint(i), Labels: map[string]string{"host": host}}))
+		}
+		assert.Equal(t, []string{"0", "1", "2", "3"}, target.bodies)
+
+		assert.NoError(t, throttler.Flush(context.Background()))
+		digest := target.bodies[4]
+		assert.Equal(t, 2, strings.Count(digest, "3 similar notifications"))
+		assert.NoError(t, throttler.Flush(context.Background()))
+		assert.Len(t, target.bodies, 5)
+		assert.NoError(t, throttler.Close())
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		target := &testRecorder{}
+		throttler := Throttle(target, WithDedupe(time.Hour), WithDigest(20*time.Millisecond))
+		for range 3 {
+			assert.NoError(t, throttler.Notify(&Notification{Body: "storm", Severity: Critical}))
+		}
+		assert.Eventually(t, func() bool {
+			target.mu.Lock()
+			defer target.mu.Unlock()
+			return len(target.bodies) == 2
+		}, time.Second, 5*time.Millisecond)
+		assert.Contains(t, target.bodies[1], "2 similar notifications")
+		assert.NoError(t, throttler.Close())
+		assert.Len(t, target.bodies, 2)
+	})
+
+	t.Run("failed delivery", func(t *testing.T) {
+		// Повтор уведомления, отправка которого не удалась, не считается дубликатом.
+		target := &testRecorder{failures: 1}
+		async := Async(Throttle(target, WithDedupe(time.Hour)), WithRetry(2, time.Millisecond))
+		assert.NoError(t, async.Notify(Text("retried")))
+		assert.NoError(t, async.Close(context.Background()))
+		assert.Equal(t, 2, target.attempts)
+		assert.Equal(t, []string{"retried"}, target.bodies)
+	})
+
+	t.Run("digest context", func(t *testing.T) {
+		target := &testRecorder{}
+		throttler := Throttle(target, WithDedupe(time.Hour))
+		assert.NoError(t, throttler.Notify(Text("storm")))
+		assert.NoError(t, throttler.Notify(Text("storm")))
+
+		// Сводка отправляется с контекстом вызова Flush и прерывается его отменой.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, throttler.Flush(ctx), context.Canceled)
+		assert.Equal(t, []string{"storm"}, target.bodies)
+		assert.NoError(t, throttler.Close())
+	})
+}
@@ -0,0 +1,99 @@
+package notifabric
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Signature" // Заголовок подписи webhook: sha256=<hex>.
+	TimestampHeader = "X-Timestamp" // Заголовок времени подписи webhook в секундах Unix.
+)
+
+// WebhookConfig есть конфигурация уведомителя, отправляющего подписанные webhook.
+// Подпись есть HMAC-SHA256 от строки "<время>.<тело>", что защищает от повторной отправки перехваченного запроса.
+type WebhookConfig struct {
+	URL      string       `json:"url"`
+	Secret   string       `json:"secret"`
+	Template *Template    `json:"template,omitempty"` // По умолчанию уведомление целиком в JSON.
+	Timeout  Duration     `json:"timeout,omitempty"`  // Ограничение времени запроса; по умолчанию 10 секунд.
+	Client   *http.Client `json:"-"`                  // Клиент для переиспользования соединений; по умолчанию создаётся свой.
+}
+
+func newWebhookNotifier(config WebhookConfig) (Notifier, error) {
+	if config.URL == "" || config.Secret == "" {
+		return nil, fmt.Errorf("url and secret are required")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = Duration(10 * time.Second)
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{}
+	}
+	return &webhookNotifier{config, orDefault(config.Template, MustTemplate(JSONTemplate("")))}, nil
+}
+
+type webhookNotifier struct {
+	config WebhookConfig
+	tmpl   *Template
+}
+
+func (slf *webhookNotifier) Notify(n *Notification) error {
+	return slf.NotifyContext(context.Background(), n)
+}
+
+func (slf *webhookNotifier) NotifyContext(ctx context.Context, n *Notification) error {
+	body, err := slf.tmpl.Render(n)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(slf.config.Timeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slf.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", slf.tmpl.ContentType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(slf.config.Secret, timestamp, body))
+	return send(slf.config.Client, req)
+}
+
+// Sign вычисляет подпись webhook для времени и тела запроса.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook проверяет подпись входящего webhook и возвращает его тело.
+// Запрос, подписанный раньше чем tolerance назад или позже текущего времени на tolerance, отвергается.
+func VerifyWebhook(secret string, r *http.Request, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := r.Header.Get(TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed timestamp %q", timestamp)
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return nil, fmt.Errorf("timestamp is out of tolerance: %v", age)
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(r.Header.Get(SignatureHeader))) {
+		return nil, fmt.Errorf("signature mismatch")
+	}
+	return body, nil
+}
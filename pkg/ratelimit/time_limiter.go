@@ -1,6 +1,7 @@
 package ratelimit
 
 import (
+	"slices"
 	"sync"
 	"time"
 )
@@ -27,12 +28,12 @@ func (slf *TimeLimiter) Allow() bool {
 	now := time.Now()
 	border := now.Add(-slf.window)
 
-	for i, timestamp := range slf.list {
-		if timestamp.After(border) {
-			slf.list = slf.list[i:]
-			break
-		}
+	// Отбрасываем отметки, вышедшие за пределы окна, в том числе когда устарели все.
+	i := slices.IndexFunc(slf.list, func(timestamp time.Time) bool { return timestamp.After(border) })
+	if i < 0 {
+		i = len(slf.list)
 	}
+	slf.list = slf.list[i:]
 
 	if len(slf.list) < slf.limit {
 		slf.list = append(slf.list, now)
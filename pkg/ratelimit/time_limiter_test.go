@@ -0,0 +1,22 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeLimiter(t *testing.T) {
+	window := 20 * time.Millisecond
+	limiter := NewTimeLimiter(2, window)
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+
+	// Когда все отметки устарели, окно освобождается целиком.
+	time.Sleep(window + 10*time.Millisecond)
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+}
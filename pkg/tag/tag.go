@@ -1,34 +1,111 @@
 package tag
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 )
 
+// Key есть ключ атрибута, в котором обработчик slog передаёт имена тега.
+const Key = "tag"
+
 type LogFunc func(string, ...any)
 
 type Tag struct {
 	logFunc        LogFunc
 	tag            string
 	defaultLogFunc bool
+	names          []string
+	attrs          []slog.Attr
+	level          slog.Level
+	logger         *slog.Logger
+	handler        slog.Handler // Обработчик журнала logger, обёрнутый Handler; строится при изменении тега.
 }
 
+type contextKey struct{}
+
 func New(tags ...string) *Tag {
 	return &Tag{
 		logFunc:        log.Printf,
 		defaultLogFunc: true,
-		tag:            "[" + strings.Join(tags, "][") + "] ",
+		tag:            prefix(tags),
+		names:          slices.Clone(tags),
 	}
 }
 
+// NewContext возвращает копию контекста, хранящую тег.
+func NewContext(ctx context.Context, t *Tag) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext возвращает тег, сохранённый в контексте через NewContext, либо тег без имён.
+func FromContext(ctx context.Context) *Tag {
+	if t, ok := ctx.Value(contextKey{}).(*Tag); ok && t != nil {
+		return t
+	}
+	return New()
+}
+
+// With возвращает дочерний тег, имена которого дополнены подтегами. Родительский тег не изменяется.
+func (slf *Tag) With(subtags ...string) *Tag {
+	t := slf.clone()
+	t.names = append(t.names, subtags...)
+	t.tag = prefix(t.names)
+	t.wrap()
+	return t
+}
+
 func (slf Tag) T(input string) string { return fmt.Sprint(slf.tag, input) }
 
-func (slf *Tag) Log(template string, args ...any) {
+// Log выводит сообщение с уровнем Info.
+func (slf *Tag) Log(template string, args ...any) { slf.log(slog.LevelInfo, template, args...) }
+
+// Debug выводит сообщение с уровнем Debug.
+func (slf *Tag) Debug(template string, args ...any) { slf.log(slog.LevelDebug, template, args...) }
+
+// Warn выводит сообщение с уровнем Warn.
+func (slf *Tag) Warn(template string, args ...any) { slf.log(slog.LevelWarn, template, args...) }
+
+// Logf выводит сообщение с заданным уровнем, если он не ниже уровня тега.
+// При заданном журнале slog сообщение передаётся в него с именами и атрибутами тега,
+// иначе в функцию журналирования с префиксом тега, уровнем, отличным от Info, и атрибутами в виде key=value.
+func (slf *Tag) Logf(level slog.Level, template string, args ...any) {
+	slf.log(level, template, args...)
+}
+
+// log вызывается непосредственно из публичных методов, чтобы источником записи slog был их вызов.
+func (slf *Tag) log(level slog.Level, template string, args ...any) {
+	if level < slf.level {
+		return
+	}
+	if slf.logger != nil {
+		ctx, h := context.Background(), slf.handler
+		if !h.Enabled(ctx, level) {
+			return
+		}
+		var pcs [1]uintptr
+		runtime.Callers(3, pcs[:])
+		_ = h.Handle(ctx, slog.NewRecord(time.Now(), level, fmt.Sprintf(template, args...), pcs[0]))
+		return
+	}
+
+	prefix := slf.tag
+	if level != slog.LevelInfo {
+		prefix += level.String() + " "
+	}
+	template = strings.ReplaceAll(prefix, "%", "%%") + template
+	for _, v := range slf.attrs {
+		template += " " + strings.ReplaceAll(v.String(), "%", "%%")
+	}
 	if slf.defaultLogFunc {
 		template += "\n"
 	}
-	slf.logFunc(slf.T(template), args...)
+	slf.logFunc(template, args...)
 }
 
 func (slf *Tag) Errorf(template string, args ...any) error {
@@ -37,10 +114,84 @@ func (slf *Tag) Errorf(template string, args ...any) error {
 
 func (slf *Tag) Error(err error) error { return fmt.Errorf("%v%w", slf.tag, err) }
 
+// WithLogFunc возвращает копию тега, выводящую сообщения через заданную функцию журналирования.
 func (slf *Tag) WithLogFunc(logFunc LogFunc) *Tag {
+	t := slf.clone()
 	if logFunc != nil {
-		slf.logFunc = logFunc
-		slf.defaultLogFunc = false
+		t.logFunc = logFunc
+		t.defaultLogFunc = false
 	}
-	return slf
+	return t
+}
+
+// WithAttrs возвращает копию тега, дополненную атрибутами. Аргументы разбираются так же, как в slog.Logger.With.
+func (slf *Tag) WithAttrs(args ...any) *Tag {
+	t := slf.clone()
+	t.attrs = append(t.attrs, slog.Group("", args...).Value.Group()...)
+	t.wrap()
+	return t
+}
+
+// WithLevel возвращает копию тега, выводящую сообщения не ниже заданного уровня.
+func (slf *Tag) WithLevel(level slog.Level) *Tag {
+	t := slf.clone()
+	t.level = level
+	t.wrap()
+	return t
+}
+
+// WithLogger возвращает копию тега, выводящую сообщения в журнал slog через обработчик Handler.
+func (slf *Tag) WithLogger(logger *slog.Logger) *Tag {
+	t := slf.clone()
+	t.logger = logger
+	t.wrap()
+	return t
+}
+
+// Handler оборачивает обработчик slog: к записям добавляются имена тега под ключом Key и атрибуты тега,
+// а записи ниже уровня тега отбрасываются.
+func (slf *Tag) Handler(next slog.Handler) slog.Handler {
+	attrs := append([]slog.Attr{slog.Any(Key, slices.Clone(slf.names))}, slf.attrs...)
+	return &handler{next.WithAttrs(attrs), slf.level}
+}
+
+// wrap строит обработчик журнала slog по текущим именам, атрибутам и уровню тега,
+// чтобы не оборачивать обработчик при каждой записи.
+func (slf *Tag) wrap() {
+	slf.handler = nil
+	if slf.logger != nil {
+		slf.handler = slf.Handler(slf.logger.Handler())
+	}
+}
+
+func (slf *Tag) clone() *Tag {
+	t := *slf
+	t.names = slices.Clip(t.names)
+	t.attrs = slices.Clip(t.attrs)
+	return &t
+}
+
+// prefix формирует префикс сообщений вида "[a][b] "; у тега без имён префикса нет.
+func prefix(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(names, "][") + "] "
+}
+
+type handler struct {
+	slog.Handler
+	level slog.Level
+}
+
+func (slf *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slf.level && slf.Handler.Enabled(ctx, level)
+}
+
+func (slf *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{slf.Handler.WithAttrs(attrs), slf.level}
+}
+
+func (slf *handler) WithGroup(name string) slog.Handler {
+	return &handler{slf.Handler.WithGroup(name), slf.level}
 }
@@ -0,0 +1,114 @@
+package tag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTag(t *testing.T) {
+	var lines []string
+	logFunc := func(template string, args ...any) { lines = append(lines, fmt.Sprintf(template, args...)) }
+
+	// Строковый API не изменился.
+	tag := New("server", "127.0.0.1:80").WithLogFunc(logFunc)
+	tag.Log("read %v bytes", 5)
+	assert.Equal(t, []string{"[server][127.0.0.1:80] read 5 bytes"}, lines)
+	assert.EqualError(t, tag.Errorf("failed: %w", errors.New("eof")), "[server][127.0.0.1:80] failed: eof")
+	assert.Equal(t, "[server][127.0.0.1:80] closed", tag.T("closed"))
+
+	// Атрибуты и уровни выводятся в текстовом виде; уровень тега отсекает сообщения ниже него.
+	lines = nil
+	child := tag.WithAttrs("conn", 7, slog.String("user", "50%")).WithLevel(slog.LevelDebug)
+	child.Debug("read %v bytes", 5)
+	child.Warn("slow")
+	tag.Debug("dropped")
+	assert.Equal(t, []string{
+		"[server][127.0.0.1:80] DEBUG read 5 bytes conn=7 user=50%",
+		"[server][127.0.0.1:80] WARN slow conn=7 user=50%",
+	}, lines)
+}
+
+func TestTagSlog(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	tag := New("server", "127.0.0.1:80").WithAttrs("conn", 7).WithLogger(logger)
+	tag.Log("read %v bytes", 5)
+	tag.Debug("dropped")
+	tag.WithLevel(slog.LevelDebug).Logf(slog.LevelError, "failed")
+
+	assert.Equal(t, []map[string]any{
+		{"level": "INFO", "msg": "read 5 bytes", "tag": []any{"server", "127.0.0.1:80"}, "conn": 7.0},
+		{"level": "ERROR", "msg": "failed", "tag": []any{"server", "127.0.0.1:80"}, "conn": 7.0},
+	}, records(t, out))
+
+	// Обработчик тега можно использовать с собственным журналом slog.
+	out.Reset()
+	slog.New(New("worker").Handler(slog.NewJSONHandler(out, nil))).Info("started", "id", 1)
+	assert.Equal(t, []map[string]any{{"level": "INFO", "msg": "started", "tag": []any{"worker"}, "id": 1.0}}, records(t, out))
+}
+
+func TestTagHandlerCache(t *testing.T) {
+	// Обработчик оборачивается при изменении тега, а не при каждой записи.
+	counter := &countingHandler{Handler: slog.NewJSONHandler(&bytes.Buffer{}, nil)}
+	tag := New("server").WithAttrs("conn", 7).WithLogger(slog.New(counter))
+	assert.Equal(t, 1, counter.wrapped)
+	for range 3 {
+		tag.Log("read")
+	}
+	assert.Equal(t, 1, counter.wrapped)
+	tag.With("child").WithLevel(slog.LevelDebug).Debug("read")
+	assert.Equal(t, 3, counter.wrapped)
+}
+
+// countingHandler подсчитывает вызовы WithAttrs.
+type countingHandler struct {
+	slog.Handler
+	wrapped int
+}
+
+func (slf *countingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	slf.wrapped++
+	return slf.Handler.WithAttrs(attrs)
+}
+
+// records разбирает записи журнала в JSON без времени.
+func records(t *testing.T, out *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, v := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		record := map[string]any{}
+		assert.NoError(t, json.Unmarshal(v, &record))
+		delete(record, "time")
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestTagWith(t *testing.T) {
+	var lines []string
+	logFunc := func(template string, args ...any) { lines = append(lines, fmt.Sprintf(template, args...)) }
+
+	// Дочерние теги и смена функции журналирования не изменяют родительский тег.
+	parent := New("server")
+	logged := parent.WithLogFunc(logFunc)
+	child := logged.WithAttrs("conn", 7).With("127.0.0.1:80")
+	sibling := logged.With("127.0.0.1:81")
+	child.Log("read")
+	sibling.Log("read")
+	logged.Log("listening")
+	assert.Equal(t, []string{"[server][127.0.0.1:80] read conn=7", "[server][127.0.0.1:81] read", "[server] listening"}, lines)
+	assert.Equal(t, "[server] x", parent.T("x"))
+	assert.EqualError(t, child.Errorf("eof"), "[server][127.0.0.1:80] eof")
+
+	// Тег передаётся через контекст; без него используется тег без имён.
+	ctx := NewContext(context.Background(), child)
+	assert.Same(t, child, FromContext(ctx))
+	assert.Equal(t, "x", FromContext(context.Background()).T("x"))
+}
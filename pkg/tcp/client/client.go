@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bufio"
 	"licklib/pkg/tag"
 	"net"
 	"time"
@@ -63,6 +64,20 @@ func (slf *Client) Read() error {
 	}
 }
 
+// ReadLines построчно читает сообщения из соединения и передаёт их обработчику, пока соединение не будет закрыто.
+// Переданный обработчику срез действителен только до возврата из него.
+func (slf *Client) ReadLines(handler func(line []byte)) error {
+	scn := bufio.NewScanner(slf.conn)
+	scn.Buffer(make([]byte, bufferSize), bufferSize)
+	for scn.Scan() {
+		handler(scn.Bytes())
+	}
+	if err := scn.Err(); err != nil {
+		return slf.t.Errorf("failed to read from connection: %v", err)
+	}
+	return nil
+}
+
 func (slf *Client) Close() {
 	if err := slf.conn.Close(); err != nil {
 		slf.t.Log("failed to close connection: %v", err)
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"context"
 	"licklib/pkg/tag"
 	"net"
+	"sync"
 )
 
 const bufferSize = 1 << 20
@@ -12,14 +14,40 @@ type Server interface {
 	Close()
 }
 
+// Handler обрабатывает установленное соединение. Контекст отменяется при закрытии сервера
+// и хранит тег соединения, доступный через tag.FromContext.
+// После возврата из обработчика соединение закрывается сервером.
+type Handler func(ctx context.Context, conn net.Conn)
+
 type server struct {
 	t        *tag.Tag
+	mu       sync.Mutex
 	listener net.Listener
+	handler  Handler
+	ctx      context.Context
+	cancel   context.CancelFunc
 	address  string
 }
 
+// NewServer создаёт эхо-сервер, отвечающий на каждое сообщение им же самим.
 func NewServer(name, address string) (Server, error) {
-	return &server{t: tag.New(name, address), address: address}, nil
+	s := newServer(name, address, nil)
+	s.handler = s.echo
+	return s, nil
+}
+
+// NewHandlerServer создаёт сервер, обрабатывающий соединения переданным обработчиком.
+func NewHandlerServer(name, address string, handler Handler) (Server, error) {
+	if handler == nil {
+		return nil, tag.New(name, address).Errorf("nil handler")
+	}
+	return newServer(name, address, handler), nil
+}
+
+func newServer(name, address string, handler Handler) *server {
+	s := &server{t: tag.New(name, address), address: address, handler: handler}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
 }
 
 func (slf *server) Serve() error {
@@ -27,7 +55,14 @@ func (slf *server) Serve() error {
 	if err != nil {
 		return slf.t.Errorf("failed to start listening on address <%v>: %w", slf.address, err)
 	}
+	slf.mu.Lock()
+	if slf.ctx.Err() != nil {
+		slf.mu.Unlock()
+		listener.Close()
+		return slf.t.Errorf("server is closed")
+	}
 	slf.listener = listener
+	slf.mu.Unlock()
 
 	slf.t.Log("waiting for connection...")
 	for {
@@ -41,37 +76,50 @@ func (slf *server) Serve() error {
 }
 
 func (slf *server) serve(conn net.Conn) {
-	remotaAddress := conn.RemoteAddr().String()
+	t := slf.t.With(conn.RemoteAddr().String())
 	defer func() {
 		if err := conn.Close(); err != nil {
-			slf.t.Log("failed to close connection with <%v>: %v", remotaAddress, err)
+			t.Log("failed to close connection: %v", err)
 			return
 		}
-		slf.t.Log("connection with <%v> closed", remotaAddress)
+		t.Log("connection closed")
 	}()
 
+	slf.handler(tag.NewContext(slf.ctx, t), conn)
+}
+
+func (slf *server) echo(ctx context.Context, conn net.Conn) {
+	t := tag.FromContext(ctx)
 	buf := make([]byte, bufferSize)
 	for {
 		n, err := conn.Read(buf)
 		if err != nil {
-			slf.t.Log("failed to read from <%v>: %v", remotaAddress, err)
+			t.Log("failed to read: %v", err)
 			return
 		}
 		message := buf[:n]
-		slf.t.Log("read %v bytes message from <%v>: %v", n, remotaAddress, string(message))
+		t.Log("read %v bytes message: %v", n, string(message))
 
 		answer := append([]byte("echo... "), message...)
 		n, err = conn.Write(answer)
 		if err != nil {
-			slf.t.Log("failed to answer: %v", err)
+			t.Log("failed to answer: %v", err)
 			continue
 		}
-		slf.t.Log("sent %v bytes answer to <%v>: %v", n, remotaAddress, string(answer))
+		t.Log("sent %v bytes answer: %v", n, string(answer))
 	}
 }
 
 func (slf *server) Close() {
-	if err := slf.listener.Close(); err != nil {
+	slf.mu.Lock()
+	slf.cancel()
+	listener := slf.listener
+	slf.mu.Unlock()
+	if listener == nil {
+		slf.t.Log("shutdown...")
+		return
+	}
+	if err := listener.Close(); err != nil {
 		slf.t.Log("failed to close listener: %v", err)
 		return
 	}
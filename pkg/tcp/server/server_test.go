@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"licklib/pkg/tag"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerClose(t *testing.T) {
+	// Сервер можно закрыть до запуска; запуск после закрытия завершается ошибкой.
+	s, err := NewHandlerServer("server", "127.0.0.1:0", func(context.Context, net.Conn) {})
+	assert.NoError(t, err)
+	assert.NotPanics(t, s.Close)
+	assert.ErrorContains(t, s.Serve(), "server is closed")
+}
+
+func TestServerHandlerTag(t *testing.T) {
+	// Обработчик получает тег соединения с именем сервера, его адресом и адресом клиента.
+	prefixes := make(chan string, 1)
+	s, err := NewHandlerServer("server", "127.0.0.1:0", func(ctx context.Context, conn net.Conn) {
+		prefixes <- tag.FromContext(ctx).T("")
+	})
+	assert.NoError(t, err)
+	defer s.Close()
+	go s.Serve()
+
+	var listener net.Listener
+	assert.Eventually(t, func() bool {
+		s.(*server).mu.Lock()
+		defer s.(*server).mu.Unlock()
+		listener = s.(*server).listener
+		return listener != nil
+	}, time.Second, time.Millisecond)
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	select {
+	case prefix := <-prefixes:
+		assert.Equal(t, "[server][127.0.0.1:0]["+conn.LocalAddr().String()+"] ", prefix)
+	case <-time.After(time.Second):
+		t.Fatal("handler was not called")
+	}
+}
@@ -31,12 +31,12 @@ func (slf *CondQueue[T]) Enqueue(value T) bool {
 	slf.mu.Lock()
 	defer slf.mu.Unlock()
 
+	for !slf.closed && len(slf.queue) >= slf.capacity {
+		slf.notFull.Wait()
+	}
 	if slf.closed {
 		return false
 	}
-	for len(slf.queue) >= slf.capacity {
-		slf.notFull.Wait()
-	}
 
 	slf.queue = append(slf.queue, value)
 	slf.notEmpty.Signal()
@@ -48,12 +48,12 @@ func (slf *CondQueue[T]) Dequeue() (T, bool) {
 	slf.mu.Lock()
 	defer slf.mu.Unlock()
 
+	for !slf.closed && len(slf.queue) == 0 {
+		slf.notEmpty.Wait()
+	}
 	if slf.closed {
 		return *new(T), false
 	}
-	for len(slf.queue) == 0 {
-		slf.notEmpty.Wait()
-	}
 
 	value := slf.queue[0]
 	slf.queue = slf.queue[1:]
@@ -62,8 +62,11 @@ func (slf *CondQueue[T]) Dequeue() (T, bool) {
 	return value, true
 }
 
+// Close закрывает очередь. Ожидающие вызовы Enqueue и Dequeue пробуждаются и возвращают false.
 func (slf *CondQueue[T]) Close() {
 	slf.mu.Lock()
 	defer slf.mu.Unlock()
 	slf.closed = true
+	slf.notEmpty.Broadcast()
+	slf.notFull.Broadcast()
 }
@@ -3,6 +3,7 @@ package condqueue
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -36,3 +37,34 @@ func TestCondQueue(t *testing.T) {
 	_, ok := q.Dequeue()
 	assert.False(t, ok)
 }
+
+func TestCondQueueClose(t *testing.T) {
+	// Вызовы, ожидающие места или значения, пробуждаются закрытием очереди и возвращают false.
+	full, empty := New[int](1), New[int](1)
+	assert.True(t, full.Enqueue(0))
+	enqueued, dequeued := make(chan bool), make(chan bool)
+	go func() { enqueued <- full.Enqueue(1) }()
+	go func() {
+		_, ok := empty.Dequeue()
+		dequeued <- ok
+	}()
+
+	select {
+	case <-enqueued:
+		t.Fatal("enqueue to a full queue returned before close")
+	case <-dequeued:
+		t.Fatal("dequeue from an empty queue returned before close")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	full.Close()
+	empty.Close()
+	for _, v := range []chan bool{enqueued, dequeued} {
+		select {
+		case ok := <-v:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("blocked call did not return after close")
+		}
+	}
+}